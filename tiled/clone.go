@@ -0,0 +1,359 @@
+package tiled
+
+import "encoding/xml"
+
+// Clone returns a deep copy of the Map. TileDefs of the copy reference the copied Tilesets and Tiles, so the copy
+// shares no mutable state with the original; only the Project the Map was loaded against is shared, as it describes
+// the classes of the Map rather than the Map itself.
+func (t *Map) Clone() *Map {
+	c := cloner{
+		tilesets: map[*Tileset]*Tileset{},
+		tiles:    map[*Tile]*Tile{},
+		layers:   map[Layer]Layer{},
+	}
+
+	m := *t
+	m.Project, m.options = t.Project, t.options
+	m.Properties = c.properties(t.Properties)
+	m.UnknownAttrs, m.UnknownElements = cloneUnknown(t.UnknownAttrs, t.UnknownElements)
+	if t.EditorSettings != nil {
+		es := *t.EditorSettings
+		if es.Export != nil {
+			e := *es.Export
+			es.Export = &e
+		}
+		m.EditorSettings = &es
+	}
+
+	if t.Tilesets != nil {
+		tss := make(Tilesets, len(*t.Tilesets))
+		for i, ts := range *t.Tilesets {
+			tss[i] = c.tileset(ts)
+		}
+		m.Tilesets = &tss
+	}
+
+	m.TileLayers = c.tileLayers(t.TileLayers)
+	m.ObjectLayers = c.objectLayers(t.ObjectLayers)
+	m.ImageLayers = c.imageLayers(t.ImageLayers)
+	m.Groups = c.groups(t.Groups)
+	m.Layers = c.ordered(t.Layers)
+
+	return &m
+}
+
+// cloner tracks copied Tilesets and Tiles so references to them can be remapped to their copies
+type cloner struct {
+	tilesets map[*Tileset]*Tileset
+	tiles    map[*Tile]*Tile
+	layers   map[Layer]Layer
+}
+
+// ordered maps Layers to their copies, keeping their order
+func (c cloner) ordered(layers []Layer) []Layer {
+	if layers == nil {
+		return nil
+	}
+
+	nl := make([]Layer, len(layers))
+	for i, l := range layers {
+		nl[i] = c.layers[l]
+	}
+	return nl
+}
+
+func (c cloner) properties(pl *Properties) *Properties {
+	if pl == nil {
+		return nil
+	}
+
+	cp := make(Properties, len(*pl))
+	for i, p := range *pl {
+		np := *p
+		np.Properties = c.properties(p.Properties)
+		np.UnknownAttrs, np.UnknownElements = cloneUnknown(p.UnknownAttrs, p.UnknownElements)
+		cp[i] = &np
+	}
+	return &cp
+}
+
+func (c cloner) image(i *Image) *Image {
+	if i == nil {
+		return nil
+	}
+
+	ni := *i
+	ni.Data = c.data(i.Data)
+	return &ni
+}
+
+func (c cloner) data(d *Data) *Data {
+	if d == nil {
+		return nil
+	}
+
+	nd := *d
+	nd.RawBytes = cloneSlice(d.RawBytes)
+	return &nd
+}
+
+func (c cloner) tileset(ts *Tileset) *Tileset {
+	if ts == nil {
+		return nil
+	}
+	if nts, ok := c.tilesets[ts]; ok {
+		return nts
+	}
+
+	nts := *ts
+	c.tilesets[ts] = &nts
+
+	nts.Properties = c.properties(ts.Properties)
+	nts.UnknownAttrs, nts.UnknownElements = cloneUnknown(ts.UnknownAttrs, ts.UnknownElements)
+	if ts.TileOffset != nil {
+		to := *ts.TileOffset
+		nts.TileOffset = &to
+	}
+	if ts.TerrainTypes != nil {
+		tts := make([]*Terrain, len(*ts.TerrainTypes))
+		for i, tt := range *ts.TerrainTypes {
+			ntt := *tt
+			ntt.Properties = c.properties(tt.Properties)
+			tts[i] = &ntt
+		}
+		nts.TerrainTypes = &tts
+	}
+	if ts.WangSets != nil {
+		wss := make(WangSets, len(*ts.WangSets))
+		for i, ws := range *ts.WangSets {
+			wss[i] = c.wangSet(ws)
+		}
+		nts.WangSets = &wss
+	}
+	if ts.Tiles != nil {
+		tiles := make(Tiles, len(*ts.Tiles))
+		for i, tile := range *ts.Tiles {
+			tiles[i] = c.tile(tile)
+		}
+		nts.Tiles = &tiles
+		nts.adoptTiles()
+	}
+	if ts.Transformations != nil {
+		tr := *ts.Transformations
+		nts.Transformations = &tr
+	}
+
+	// A Tileset without an image of its own borrows the image of one of its Tiles
+	nts.Image = c.image(ts.Image)
+	if ts.Tiles != nil {
+		for i, tile := range *ts.Tiles {
+			if tile.Image != nil && tile.Image == ts.Image {
+				nts.Image = (*nts.Tiles)[i].Image
+			}
+		}
+	}
+
+	return &nts
+}
+
+func (c cloner) wangSet(ws *WangSet) *WangSet {
+	nws := *ws
+	nws.Properties = c.properties(ws.Properties)
+	if ws.WangColors != nil {
+		wcs := make([]*WangColor, len(*ws.WangColors))
+		for i, wc := range *ws.WangColors {
+			nwc := *wc
+			nwc.Properties = c.properties(wc.Properties)
+			wcs[i] = &nwc
+		}
+		nws.WangColors = &wcs
+	}
+	if ws.WangTiles != nil {
+		wts := make([]*WangTile, len(*ws.WangTiles))
+		for i, wt := range *ws.WangTiles {
+			nwt := *wt
+			wts[i] = &nwt
+		}
+		nws.WangTiles = &wts
+	}
+	return &nws
+}
+
+func (c cloner) tile(t *Tile) *Tile {
+	if t == nil {
+		return nil
+	}
+	if nt, ok := c.tiles[t]; ok {
+		return nt
+	}
+
+	nt := *t
+	c.tiles[t] = &nt
+
+	nt.Properties = c.properties(t.Properties)
+	nt.UnknownAttrs, nt.UnknownElements = cloneUnknown(t.UnknownAttrs, t.UnknownElements)
+	nt.Image = c.image(t.Image)
+	if t.Animation != nil {
+		a := make(Animation, len(*t.Animation))
+		for i, f := range *t.Animation {
+			nf := *f
+			a[i] = &nf
+		}
+		nt.Animation = &a
+	}
+	nt.ObjectLayer = c.objectLayer(t.ObjectLayer)
+	if t.TerrainType != nil {
+		tt := *t.TerrainType
+		nt.TerrainType = &tt
+	}
+	return &nt
+}
+
+func (c cloner) tileLayers(tls *TileLayers) *TileLayers {
+	if tls == nil {
+		return nil
+	}
+
+	ntls := make(TileLayers, len(*tls))
+	for i, l := range *tls {
+		nl := *l
+		nl.Properties = c.properties(l.Properties)
+		nl.UnknownAttrs, nl.UnknownElements = cloneUnknown(l.UnknownAttrs, l.UnknownElements)
+		nl.RawData = c.data(l.RawData)
+		if l.TileGlobalRefs != nil {
+			nl.TileGlobalRefs = make([]*TileGlobalRef, len(l.TileGlobalRefs))
+			for j, tgr := range l.TileGlobalRefs {
+				ntgr := *tgr
+				nl.TileGlobalRefs[j] = &ntgr
+			}
+		}
+		if l.TileDefs != nil {
+			nl.TileDefs = make([]*TileDef, len(l.TileDefs))
+			for j, td := range l.TileDefs {
+				ntd := *td
+				ntd.TileSet = c.tileset(td.TileSet)
+				ntd.Tile = c.tile(td.Tile)
+				nl.TileDefs[j] = &ntd
+			}
+		}
+		nl.LayerData = cloneSlice(l.LayerData)
+		ntls[i] = &nl
+		c.layers[l] = &nl
+	}
+	return &ntls
+}
+
+func (c cloner) objectLayers(ols *ObjectLayers) *ObjectLayers {
+	if ols == nil {
+		return nil
+	}
+
+	nols := make(ObjectLayers, len(*ols))
+	for i, ol := range *ols {
+		nols[i] = c.objectLayer(ol)
+		c.layers[ol] = nols[i]
+	}
+	return &nols
+}
+
+func (c cloner) objectLayer(ol *ObjectLayer) *ObjectLayer {
+	if ol == nil {
+		return nil
+	}
+
+	nol := *ol
+	nol.Properties = c.properties(ol.Properties)
+	nol.UnknownAttrs, nol.UnknownElements = cloneUnknown(ol.UnknownAttrs, ol.UnknownElements)
+	nol.index = nil
+	if ol.Objects != nil {
+		objects := make(Objects, len(*ol.Objects))
+		for i, o := range *ol.Objects {
+			objects[i] = c.object(o)
+		}
+		nol.Objects = &objects
+	}
+	return &nol
+}
+
+func (c cloner) object(o *Object) *Object {
+	no := *o
+	no.Properties = c.properties(o.Properties)
+	no.UnknownAttrs, no.UnknownElements = cloneUnknown(o.UnknownAttrs, o.UnknownElements)
+	no.Image = c.image(o.Image)
+	if o.Polygon != nil {
+		p := *o.Polygon
+		no.Polygon = &p
+	}
+	if o.Polyline != nil {
+		p := *o.Polyline
+		no.Polyline = &p
+	}
+	if o.Text != nil {
+		t := *o.Text
+		no.Text = &t
+	}
+	if o.Point != nil {
+		no.Point = &struct{}{}
+	}
+	if o.Ellipse != nil {
+		no.Ellipse = &struct{}{}
+	}
+	// The template the Object was loaded from is recorded by path, so the copy validates against its own Tilesets
+	no.templatePath, no.templateTileset, no.templateFirstGID = o.templatePath, o.templateTileset, o.templateFirstGID
+	no.project = o.project
+	return &no
+}
+
+func (c cloner) imageLayers(ils *ImageLayers) *ImageLayers {
+	if ils == nil {
+		return nil
+	}
+
+	nils := make(ImageLayers, len(*ils))
+	for i, il := range *ils {
+		nl := *il
+		nl.Properties = c.properties(il.Properties)
+		nl.UnknownAttrs, nl.UnknownElements = cloneUnknown(il.UnknownAttrs, il.UnknownElements)
+		nl.Image = c.image(il.Image)
+		nils[i] = &nl
+		c.layers[il] = &nl
+	}
+	return &nils
+}
+
+func (c cloner) groups(gs *Groups) *Groups {
+	if gs == nil {
+		return nil
+	}
+
+	ngs := make(Groups, len(*gs))
+	for i, g := range *gs {
+		ng := *g
+		ng.Properties = c.properties(g.Properties)
+		ng.UnknownAttrs, ng.UnknownElements = cloneUnknown(g.UnknownAttrs, g.UnknownElements)
+		ng.TileLayers = c.tileLayers(g.TileLayers)
+		ng.ObjectLayers = c.objectLayers(g.ObjectLayers)
+		ng.ImageLayers = c.imageLayers(g.ImageLayers)
+		ng.Groups = c.groups(g.Groups)
+		ng.Layers = c.ordered(g.Layers)
+		ng.adopt()
+		ngs[i] = &ng
+		c.layers[g] = &ng
+	}
+	return &ngs
+}
+
+func cloneUnknown(attrs []xml.Attr, elements []UnknownElement) ([]xml.Attr, []UnknownElement) {
+	ne := cloneSlice(elements)
+	for i := range ne {
+		ne[i].Attrs = cloneSlice(ne[i].Attrs)
+	}
+	return cloneSlice(attrs), ne
+}
+
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
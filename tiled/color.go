@@ -0,0 +1,41 @@
+package tiled
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// white is the identity for multiplying colors
+var white = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+
+// DefaultObjectColor is the color Tiled draws Objects with when their ObjectLayer sets none, `#a0a0a4`
+var DefaultObjectColor = color.RGBA{R: 0xa0, G: 0xa0, B: 0xa4, A: 0xff}
+
+// ParseColor parses a Tiled color in the form `#RRGGBB` or `#AARRGGBB`; the leading `#` is optional
+func ParseColor(s string) (color.RGBA, error) {
+	h := strings.TrimPrefix(s, "#")
+	if len(h) != 6 && len(h) != 8 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q: %w", s, err)
+	}
+
+	c := color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+	if len(h) == 8 {
+		c.A = uint8(v >> 24)
+	}
+	return c, nil
+}
+
+// multiplyColor multiplies the channels of a and b, each scaled to the range [0, 1]
+func multiplyColor(a, b color.RGBA) color.RGBA {
+	mul := func(x, y uint8) uint8 {
+		return uint8((uint16(x)*uint16(y) + 127) / 255)
+	}
+	return color.RGBA{R: mul(a.R, b.R), G: mul(a.G, b.G), B: mul(a.B, b.B), A: mul(a.A, b.A)}
+}
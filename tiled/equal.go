@@ -0,0 +1,139 @@
+package tiled
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Equal reports whether the Map is logically equal to other, and if not, describes the first difference found. TileDefs
+// are compared by GlobalID, Properties by name and value whatever their order, WangIDs by their colors and pointers by
+// what they point to; a nil pointer or slice equals an empty one. Parent, Project and the raw TileLayer data are not
+// compared.
+func (t *Map) Equal(other *Map) (bool, string) {
+	d := diffValues("Map", reflect.ValueOf(t), reflect.ValueOf(other))
+	return d == "", d
+}
+
+var (
+	propertiesType = reflect.TypeFor[Properties]()
+	tileDefType    = reflect.TypeFor[TileDef]()
+	wangIDType     = reflect.TypeFor[WangID]()
+)
+
+// ignoredFields holds exported fields that are references back up the tree, or encodings of data compared in decoded
+// form, by struct type
+var ignoredFields = map[reflect.Type][]string{
+	reflect.TypeFor[Map]():         {"Project"},
+	reflect.TypeFor[TileLayer]():   {"Parent", "RawData", "LayerData", "TileGlobalRefs"},
+	reflect.TypeFor[ObjectLayer](): {"Parent"},
+	reflect.TypeFor[ImageLayer]():  {"Parent"},
+	reflect.TypeFor[Group]():       {"Parent"},
+	reflect.TypeFor[Tile]():        {"Tileset"},
+}
+
+// diffValues returns the first difference between a and b, named from path; empty if they are equal
+func diffValues(path string, a, b reflect.Value) string {
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if isEmpty(a) && isEmpty(b) {
+				return ""
+			}
+			return fmt.Sprintf("%s: one is nil", path)
+		}
+		if a.Kind() == reflect.Interface && a.Elem().Type() != b.Elem().Type() {
+			return fmt.Sprintf("%s: %s != %s", path, a.Elem().Type(), b.Elem().Type())
+		}
+		return diffValues(path, a.Elem(), b.Elem())
+
+	case reflect.Struct:
+		if a.Type() == tileDefType {
+			return diffTileDefs(path, a.Interface().(TileDef), b.Interface().(TileDef))
+		}
+		ignored := ignoredFields[a.Type()]
+		for i := range a.NumField() {
+			f := a.Type().Field(i)
+			if !f.IsExported() || slices.Contains(ignored, f.Name) {
+				continue
+			}
+			if d := diffValues(path+"."+f.Name, a.Field(i), b.Field(i)); d != "" {
+				return d
+			}
+		}
+		return ""
+
+	case reflect.Slice, reflect.Array:
+		if a.Type() == propertiesType {
+			return diffProperties(path, a.Interface().(Properties), b.Interface().(Properties))
+		}
+		if a.Len() != b.Len() {
+			return fmt.Sprintf("%s: length %d != %d", path, a.Len(), b.Len())
+		}
+		for i := range a.Len() {
+			if d := diffValues(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i)); d != "" {
+				return d
+			}
+		}
+		return ""
+
+	default:
+		if a.Type() == wangIDType {
+			return diffWangIDs(path, a.Interface().(WangID), b.Interface().(WangID))
+		}
+		if !a.Equal(b) {
+			return fmt.Sprintf("%s: %v != %v", path, a, b)
+		}
+		return ""
+	}
+}
+
+// diffTileDefs compares TileDefs by GlobalID, which holds the flip flags, rather than by their Tileset and Tile pointers
+func diffTileDefs(path string, a, b TileDef) string {
+	if a.Nil != b.Nil {
+		return fmt.Sprintf("%s: Nil %t != %t", path, a.Nil, b.Nil)
+	}
+	if a.GlobalID != b.GlobalID {
+		return fmt.Sprintf("%s: GlobalID %s != %s", path, a.GlobalID, b.GlobalID)
+	}
+	return ""
+}
+
+// diffWangIDs compares WangIDs by their colors, so the legacy 0xCECECECE format equals the comma separated one; WangIDs
+// that fail to parse are compared as written
+func diffWangIDs(path string, a, b WangID) string {
+	ca, errA := a.Colors()
+	cb, errB := b.Colors()
+	if errA == nil && errB == nil && ca == cb || a == b {
+		return ""
+	}
+	return fmt.Sprintf("%s: %s != %s", path, a, b)
+}
+
+// diffProperties compares Properties by name, ignoring their order
+func diffProperties(path string, a, b Properties) string {
+	if len(a) != len(b) {
+		return fmt.Sprintf("%s: length %d != %d", path, len(a), len(b))
+	}
+	for _, pa := range a {
+		pb := b.WithName(pa.Name)
+		if pb == nil {
+			return fmt.Sprintf("%s: %q missing", path, pa.Name)
+		}
+		if d := diffValues(fmt.Sprintf("%s[%q]", path, pa.Name), reflect.ValueOf(pa), reflect.ValueOf(pb)); d != "" {
+			return d
+		}
+	}
+	return ""
+}
+
+// isEmpty reports whether v is nil or points to a zero length slice
+func isEmpty(v reflect.Value) bool {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.Slice && v.Len() == 0
+}
@@ -1,14 +1,20 @@
 package tiled
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Possible Errors
 var (
 	ErrUnsupportedEncoding      = errors.New("invalid encoding")
 	ErrUnsupportedCompression   = errors.New("unsupported compression type")
+	ErrUnsupportedFormat        = errors.New("unsupported file format")
 	ErrNoSuitableTileset        = errors.New("no suitable Tileset found for tile")
+	ErrTilesetNotFound          = errors.New("no Tileset found with the given source")
 	ErrPropertyWrongType        = errors.New("a Property was found, but its type was incorrect")
 	ErrPropertyFailedConversion = errors.New("the Property failed to convert to the expected type")
+	ErrPropertyMissing          = errors.New("a required Property was not found")
 	ErrUnknownOrientation       = errors.New("unknown orientation type")
 	ErrUnknownRenderOrder       = errors.New("unknown render order type")
 	ErrUnknownObjectAlignment   = errors.New("unknown Object alignment type")
@@ -17,12 +23,48 @@ var (
 	ErrUnknownImageFormat       = errors.New("unknown Image format type")
 	ErrUnknownDrawOrder         = errors.New("unknown draw order type")
 	ErrUnknownPropertyType      = errors.New("unknown Property type")
+	ErrUnknownCustomType        = errors.New("unknown custom Property type")
+	ErrUnknownWangSetType       = errors.New("unknown Wang set type")
+	ErrUnknownTileRenderSize    = errors.New("unknown tile render size")
+	ErrUnknownFillMode          = errors.New("unknown fill mode")
 	ErrDecodingTilemap          = errors.New("failed to decode tilemap")
 	ErrDecodingTileset          = errors.New("failed to decode tileset")
 	ErrDecodingTile             = errors.New("failed to decode tile")
+	ErrDecodingGroup            = errors.New("failed to decode group")
 	ErrDecodingTileLayer        = errors.New("failed to decode tile layer")
 	ErrDecodingTileLayerData    = errors.New("failed to decode tile layer data")
+	ErrDecodingImageLayer       = errors.New("failed to decode image layer")
 	ErrDecodingObjectLayer      = errors.New("failed to decode object layer")
+	ErrDecodingProject          = errors.New("failed to decode project")
+	ErrDecodingPoly             = errors.New("failed to decode polygon points")
+	ErrDecodingText             = errors.New("failed to decode text")
 	ErrDecodingTemplate         = errors.New("failed to decode template")
+	ErrDecodingProperty         = errors.New("failed to decode property")
+	ErrNoTileImage              = errors.New("no image found for tile")
 	ErrTileDefOutOfBounds       = errors.New("failed to get tile def out of bounds")
+	ErrLayerSizeMismatch        = errors.New("tile layer sizes do not match")
+	ErrTileCountMismatch        = errors.New("tileset tile count does not match its columns and rows")
+	ErrTileIDOutOfRange         = errors.New("tileset tile ID is out of range")
+	ErrImageSizeMismatch        = errors.New("tileset image size does not match its tile grid")
+	ErrTemplateTilesetMissing   = errors.New("template tileset is not a tileset of the map")
+	ErrTemplateTilesetMoved     = errors.New("template tileset has a different first global ID in the map")
+	ErrTemplateTilesetEmbedded  = errors.New("template tileset is embedded rather than an external tileset")
+	ErrGlobalIDOverflow         = errors.New("global ID overflows into the tile flip flags")
+	ErrFrameTileIDOutOfRange    = errors.New("animation frame tile ID is out of range")
+	ErrInvalidWangID            = errors.New("invalid Wang ID")
 )
+
+// ResourceError is returned when an external Tileset or template referenced by a Map fails to load; Path is the
+// resolved path of the offending file
+type ResourceError struct {
+	Path string
+	Err  error
+}
+
+func (e *ResourceError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
@@ -1,5 +1,10 @@
 package tiled
 
+import (
+	"encoding/xml"
+	"fmt"
+)
+
 type Groups []*Group
 
 // WithName retrieves the first Group matching the provided name. Returns `nil` if not found.
@@ -13,11 +18,12 @@ func (gl Groups) WithName(name string) *Group {
 }
 
 type Group struct {
-	Id        string  `xml:"id,attr"`
+	ID        LayerID `xml:"id,attr"`
 	Name      string  `xml:"name,attr"`
 	Class     string  `xml:"class,attr"`
 	Opacity   float32 `xml:"opacity,attr"`
 	Visible   bool    `xml:"visible,attr"`
+	Locked    bool    `xml:"locked,attr"`
 	OffsetX   int     `xml:"offsetx,attr"`
 	OffsetY   int     `xml:"offsety,attr"`
 	ParallaxX int     `xml:"parallaxx,attr"`
@@ -29,4 +35,50 @@ type Group struct {
 	ObjectLayers *ObjectLayers `xml:"objectgroup"`
 	ImageLayers  *ImageLayers  `xml:"imagelayer"`
 	Groups       *Groups       `xml:"group"`
+
+	// Layers holds every child layer and Group in document order
+	Layers []Layer `xml:"-"`
+
+	// Parent is the Group holding the layer; `nil` at the top level of the Map
+	Parent *Group `xml:"-"`
+
+	// Byte offset of the end of the element within the map document, used to recover draw order
+	offset int64
+
+	// Unrecognised attributes and elements, kept so they can be written back out
+	UnknownAttrs    []xml.Attr       `xml:",any,attr"`
+	UnknownElements []UnknownElement `xml:",any"`
+}
+
+func (g *Group) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
+	type tmpGroup Group
+	// Like Tiled, layers are visible and fully opaque unless the document says otherwise
+	tmp := tmpGroup{Opacity: 1, Visible: true}
+
+	if err := xd.DecodeElement(&tmp, &start); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingGroup, err)
+	}
+
+	*g = (Group)(tmp)
+	g.offset = xd.InputOffset()
+	g.Layers = documentOrder(g.TileLayers, g.ObjectLayers, g.ImageLayers, g.Groups)
+	g.adopt()
+
+	return nil
+}
+
+// adopt sets the Group as the Parent of each of its child layers
+func (g *Group) adopt() {
+	for _, l := range g.Layers {
+		switch l := l.(type) {
+		case *TileLayer:
+			l.Parent = g
+		case *ObjectLayer:
+			l.Parent = g
+		case *ImageLayer:
+			l.Parent = g
+		case *Group:
+			l.Parent = g
+		}
+	}
 }
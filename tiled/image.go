@@ -2,6 +2,9 @@ package tiled
 
 import (
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
 	"strings"
 )
 
@@ -17,6 +20,33 @@ type Image struct {
 	Data             *Data       `xml:"data"`
 }
 
+// ApplyColorKey returns an RGBA copy of img, the decoded pixels of the Image, with every pixel of the TransparentColor
+// made fully transparent, as Tiled draws it. Pixels match on their red, green and blue channels. Returns an unchanged
+// copy if the Image has no TransparentColor.
+func (i *Image) ApplyColorKey(img image.Image) (*image.RGBA, error) {
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+
+	if i.TransparentColor == "" {
+		return dst, nil
+	}
+	key, err := ParseColor(i.TransparentColor)
+	if err != nil {
+		return nil, err
+	}
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.R == key.R && c.G == key.G && c.B == key.B {
+				dst.SetRGBA(x, y, color.RGBA{})
+			}
+		}
+	}
+	return dst, nil
+}
+
 type ImageFormat int
 
 const (
@@ -30,7 +60,8 @@ func (i *ImageFormat) UnmarshalText(text []byte) error {
 	s := strings.ToLower(string(text))
 	switch strings.ToLower(s) {
 	default:
-		return fmt.Errorf("%w: %s", ErrUnknownImageFormat, s)
+		*i = Png
+		return unknownEnum(ErrUnknownImageFormat, s)
 	case "png":
 		*i = Png
 	case "gif":
@@ -42,3 +73,17 @@ func (i *ImageFormat) UnmarshalText(text []byte) error {
 	}
 	return nil
 }
+
+func (i ImageFormat) MarshalText() ([]byte, error) {
+	switch i {
+	case Png:
+		return []byte("png"), nil
+	case Gif:
+		return []byte("gif"), nil
+	case Jpg:
+		return []byte("jpg"), nil
+	case Bmp:
+		return []byte("bmp"), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownImageFormat, i)
+}
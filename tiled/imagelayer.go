@@ -1,5 +1,10 @@
 package tiled
 
+import (
+	"encoding/xml"
+	"fmt"
+)
+
 type ImageLayers []*ImageLayer
 
 // WithName retrieves the first ImageLayer matching the provided name. Returns `nil` if not found.
@@ -14,7 +19,7 @@ func (il ImageLayers) WithName(name string) *ImageLayer {
 
 // ImageLayer is a TileLayer consisting of a single Image, such as a background.
 type ImageLayer struct {
-	ID        string  `xml:"id,attr"`
+	ID        LayerID `xml:"id,attr"`
 	Name      string  `xml:"name,attr"`
 	Class     string  `xml:"class,attr"`
 	X         int     `xml:"x,attr"`
@@ -25,10 +30,79 @@ type ImageLayer struct {
 	ParallaxY int     `xml:"parallaxy,attr"`
 	Opacity   float32 `xml:"opacity,attr"`
 	Visible   bool    `xml:"visible,attr"`
+	Locked    bool    `xml:"locked,attr"`
 	TintColor string  `xml:"tintcolor,attr"`
 	RepeatX   bool    `xml:"repeatx,attr"`
 	RepeatY   bool    `xml:"repeaty,attr"`
 
 	Properties *Properties `xml:"properties>property"`
 	Image      *Image      `xml:"image"`
+
+	// Parent is the Group holding the layer; `nil` at the top level of the Map
+	Parent *Group `xml:"-"`
+
+	// Byte offset of the end of the element within the map document, used to recover draw order
+	offset int64
+
+	// Unrecognised attributes and elements, kept so they can be written back out
+	UnknownAttrs    []xml.Attr       `xml:",any,attr"`
+	UnknownElements []UnknownElement `xml:",any"`
+}
+
+// RepeatRects returns where the image of the ImageLayer is drawn over a map of mapW by mapH pixels, placed at the
+// layer position and offset and repeated along the axes with RepeatX or RepeatY set to cover the map. Placements are
+// for a view at the parallax origin, where parallax factors shift nothing; a scrolled view offsets them by the scroll
+// times (1 - parallax). Returns `nil` if the layer has no sized image.
+func (i *ImageLayer) RepeatRects(mapW, mapH int) []Rect {
+	if i.Image == nil || i.Image.Width <= 0 || i.Image.Height <= 0 {
+		return nil
+	}
+
+	xs := repeatStarts(i.X+i.OffsetX, i.Image.Width, mapW, i.RepeatX)
+	ys := repeatStarts(i.Y+i.OffsetY, i.Image.Height, mapH, i.RepeatY)
+
+	rects := make([]Rect, 0, len(xs)*len(ys))
+	for _, y := range ys {
+		for _, x := range xs {
+			rects = append(rects, Rect{
+				Min: Point{x, y},
+				Max: Point{x + i.Image.Width, y + i.Image.Height},
+			})
+		}
+	}
+	return rects
+}
+
+// repeatStarts returns the start of each copy of an image of size placed at start along one axis of length extent;
+// repeated copies begin at or before 0 and run until the axis is covered
+func repeatStarts(start, size, extent int, repeat bool) []int {
+	if !repeat {
+		return []int{start}
+	}
+
+	first := ((start % size) + size) % size
+	if first > 0 {
+		first -= size
+	}
+
+	var starts []int
+	for s := first; s < extent; s += size {
+		starts = append(starts, s)
+	}
+	return starts
+}
+
+func (i *ImageLayer) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
+	type tmpImageLayer ImageLayer
+	// Like Tiled, layers are visible and fully opaque unless the document says otherwise
+	tmp := tmpImageLayer{Opacity: 1, Visible: true}
+
+	if err := xd.DecodeElement(&tmp, &start); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingImageLayer, err)
+	}
+
+	*i = (ImageLayer)(tmp)
+	i.offset = xd.InputOffset()
+
+	return nil
 }
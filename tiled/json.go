@@ -0,0 +1,1446 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// WriteJSON writes the Map in the Tiled JSON map format (.tmj) https://doc.mapeditor.org/en/stable/reference/json-map-format/
+// TileLayer data is written as an array of GlobalIDs, or as a base64 string compressed as the layer was loaded when
+// its data was base64 encoded; lz4 layers are written uncompressed. Tilesets loaded from an external file are written
+// as a reference to that file.
+func (t *Map) WriteJSON(w io.Writer) error {
+	jm, err := t.toJSON()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(jm)
+}
+
+type jsonMap struct {
+	Type            string          `json:"type"`
+	Version         string          `json:"version"`
+	TiledVersion    string          `json:"tiledversion,omitempty"`
+	Class           string          `json:"class,omitempty"`
+	Orientation     Orientation     `json:"orientation"`
+	RenderOrder     RenderOrder     `json:"renderorder"`
+	Width           int             `json:"width"`
+	Height          int             `json:"height"`
+	TileWidth       int             `json:"tilewidth"`
+	TileHeight      int             `json:"tileheight"`
+	HexSideLength   int             `json:"hexsidelength,omitempty"`
+	StaggerAxis     string          `json:"staggeraxis,omitempty"`
+	StaggerIndex    string          `json:"staggerindex,omitempty"`
+	BackgroundColor string          `json:"backgroundcolor,omitempty"`
+	ParallaxOriginX float32         `json:"parallaxoriginx,omitempty"`
+	ParallaxOriginY float32         `json:"parallaxoriginy,omitempty"`
+	NextLayerID     LayerID         `json:"nextlayerid"`
+	NextObjectID    int             `json:"nextobjectid"`
+	Infinite        bool            `json:"infinite"`
+	EditorSettings  *EditorSettings `json:"editorsettings,omitempty"`
+	Properties      []*jsonProperty `json:"properties,omitempty"`
+	Tilesets        []*jsonTileset  `json:"tilesets"`
+	Layers          []*jsonLayer    `json:"layers"`
+}
+
+type jsonProperty struct {
+	Name         string       `json:"name"`
+	Type         PropertyType `json:"type"`
+	PropertyType string       `json:"propertytype,omitempty"`
+	Value        any          `json:"value"`
+}
+
+type jsonLayer struct {
+	ID         LayerID         `json:"id"`
+	Name       string          `json:"name"`
+	Class      string          `json:"class,omitempty"`
+	Type       string          `json:"type"`
+	X          float32         `json:"x"`
+	Y          float32         `json:"y"`
+	Width      int             `json:"width,omitempty"`
+	Height     int             `json:"height,omitempty"`
+	Opacity    float32         `json:"opacity"`
+	Visible    bool            `json:"visible"`
+	Locked     bool            `json:"locked,omitempty"`
+	TintColor  string          `json:"tintcolor,omitempty"`
+	OffsetX    int             `json:"offsetx,omitempty"`
+	OffsetY    int             `json:"offsety,omitempty"`
+	ParallaxX  float32         `json:"parallaxx,omitempty"`
+	ParallaxY  float32         `json:"parallaxy,omitempty"`
+	Properties []*jsonProperty `json:"properties,omitempty"`
+
+	// tilelayer
+	Encoding    string `json:"encoding,omitempty"`
+	Compression string `json:"compression,omitempty"`
+	Data        any    `json:"data,omitempty"`
+
+	// objectgroup
+	Color     string        `json:"color,omitempty"`
+	DrawOrder *DrawOrder    `json:"draworder,omitempty"`
+	Objects   []*jsonObject `json:"objects,omitempty"`
+
+	// imagelayer
+	Image            string `json:"image,omitempty"`
+	ImageWidth       int    `json:"imagewidth,omitempty"`
+	ImageHeight      int    `json:"imageheight,omitempty"`
+	TransparentColor string `json:"transparentcolor,omitempty"`
+	RepeatX          bool   `json:"repeatx,omitempty"`
+	RepeatY          bool   `json:"repeaty,omitempty"`
+
+	// group
+	Layers []*jsonLayer `json:"layers,omitempty"`
+}
+
+type jsonObject struct {
+	ID         ObjectID        `json:"id"`
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	X          float32         `json:"x"`
+	Y          float32         `json:"y"`
+	Width      float32         `json:"width"`
+	Height     float32         `json:"height"`
+	Rotation   float32         `json:"rotation"`
+	Visible    *bool           `json:"visible,omitempty"`
+	GlobalID   GlobalID        `json:"gid,omitempty"`
+	Template   string          `json:"template,omitempty"`
+	Point      bool            `json:"point,omitempty"`
+	Ellipse    bool            `json:"ellipse,omitempty"`
+	Polygon    []PointF        `json:"polygon,omitempty"`
+	Polyline   []PointF        `json:"polyline,omitempty"`
+	Text       *jsonText       `json:"text,omitempty"`
+	Properties []*jsonProperty `json:"properties,omitempty"`
+}
+
+type jsonText struct {
+	Text       string     `json:"text"`
+	FontFamily string     `json:"fontfamily"`
+	PixelSize  int        `json:"pixelsize"`
+	Wrap       bool       `json:"wrap"`
+	Color      string     `json:"color"`
+	Bold       bool       `json:"bold"`
+	Italic     bool       `json:"italic"`
+	Underline  bool       `json:"underline"`
+	Strikeout  bool       `json:"strikeout"`
+	Kerning    bool       `json:"kerning"`
+	HAlign     HAlignment `json:"halign"`
+	VAlign     VAlignment `json:"valign"`
+}
+
+type jsonTileset struct {
+	FirstGlobalID    GlobalID         `json:"firstgid"`
+	Source           string           `json:"source,omitempty"`
+	Name             string           `json:"name,omitempty"`
+	Class            string           `json:"class,omitempty"`
+	TileWidth        int              `json:"tilewidth,omitempty"`
+	TileHeight       int              `json:"tileheight,omitempty"`
+	Spacing          int              `json:"spacing,omitempty"`
+	Margin           int              `json:"margin,omitempty"`
+	TileCount        uint32           `json:"tilecount,omitempty"`
+	Columns          int              `json:"columns,omitempty"`
+	ObjectAlignment  *ObjectAlignment `json:"objectalignment,omitempty"`
+	TileRenderSize   *TileRenderSize  `json:"tilerendersize,omitempty"`
+	FillMode         *FillMode        `json:"fillmode,omitempty"`
+	Image            string           `json:"image,omitempty"`
+	ImageWidth       int              `json:"imagewidth,omitempty"`
+	ImageHeight      int              `json:"imageheight,omitempty"`
+	TransparentColor string           `json:"transparentcolor,omitempty"`
+	TileOffset       *tileOffset      `json:"tileoffset,omitempty"`
+	Transformations  *Transformations `json:"transformations,omitempty"`
+	Properties       []*jsonProperty  `json:"properties,omitempty"`
+	Terrains         []*jsonTerrain   `json:"terrains,omitempty"`
+	WangSets         []*jsonWangSet   `json:"wangsets,omitempty"`
+	Tiles            []*jsonTile      `json:"tiles,omitempty"`
+}
+
+type jsonTerrain struct {
+	Name       string          `json:"name"`
+	Tile       TileID          `json:"tile"`
+	Properties []*jsonProperty `json:"properties,omitempty"`
+}
+
+type jsonWangSet struct {
+	Name       string           `json:"name"`
+	Class      string           `json:"class,omitempty"`
+	Type       WangSetType      `json:"type"`
+	Tile       TileID           `json:"tile"`
+	Properties []*jsonProperty  `json:"properties,omitempty"`
+	Colors     []*jsonWangColor `json:"colors"`
+	WangTiles  []*jsonWangTile  `json:"wangtiles"`
+}
+
+type jsonWangColor struct {
+	Name       string          `json:"name"`
+	Class      string          `json:"class,omitempty"`
+	Color      string          `json:"color"`
+	Tile       TileID          `json:"tile"`
+	Properties []*jsonProperty `json:"properties,omitempty"`
+}
+
+type jsonWangTile struct {
+	TileID TileID `json:"tileid"`
+	WangID []int  `json:"wangid"`
+}
+
+type jsonTile struct {
+	ID          TileID          `json:"id"`
+	Type        string          `json:"type,omitempty"`
+	Probability float32         `json:"probability,omitempty"`
+	X           int             `json:"x,omitempty"`
+	Y           int             `json:"y,omitempty"`
+	Width       int             `json:"width,omitempty"`
+	Height      int             `json:"height,omitempty"`
+	Image       string          `json:"image,omitempty"`
+	ImageWidth  int             `json:"imagewidth,omitempty"`
+	ImageHeight int             `json:"imageheight,omitempty"`
+	Terrain     []int           `json:"terrain,omitempty"`
+	Animation   []*jsonFrame    `json:"animation,omitempty"`
+	ObjectGroup *jsonLayer      `json:"objectgroup,omitempty"`
+	Properties  []*jsonProperty `json:"properties,omitempty"`
+}
+
+type jsonFrame struct {
+	TileID   TileID `json:"tileid"`
+	Duration int    `json:"duration"`
+}
+
+func (t *Map) toJSON() (*jsonMap, error) {
+	jm := &jsonMap{
+		Type:            "map",
+		Version:         t.Version,
+		TiledVersion:    t.TiledVersion,
+		Class:           t.Class,
+		Orientation:     t.Orientation,
+		RenderOrder:     t.RenderOrder,
+		Width:           t.Width,
+		Height:          t.Height,
+		TileWidth:       t.TileWidth,
+		TileHeight:      t.TileHeight,
+		HexSideLength:   t.HexSideLength,
+		StaggerAxis:     t.StaggerAxis,
+		StaggerIndex:    t.StaggerIndex,
+		BackgroundColor: t.BackgroundColor,
+		ParallaxOriginX: t.ParallaxOriginX,
+		ParallaxOriginY: t.ParallaxOriginY,
+		NextLayerID:     t.NextLayerID,
+		NextObjectID:    t.NextObjectID,
+		Infinite:        t.Infinite,
+		Tilesets:        []*jsonTileset{},
+	}
+
+	// Like Tiled, only write editor settings that differ from the defaults
+	defaultChunkSize := ChunkSize{Width: DefaultChunkWidth, Height: DefaultChunkHeight}
+	if es := t.EditorSettings; es != nil && (es.Export != nil || es.ChunkSize != defaultChunkSize) {
+		jm.EditorSettings = es
+	}
+
+	var err error
+	if jm.Properties, err = jsonProperties(t.Properties); err != nil {
+		return nil, err
+	}
+
+	if t.Tilesets != nil {
+		for _, ts := range *t.Tilesets {
+			jts, err := jsonTilesetOf(ts)
+			if err != nil {
+				return nil, err
+			}
+			jm.Tilesets = append(jm.Tilesets, jts)
+		}
+	}
+
+	if jm.Layers, err = jsonLayers(t.Layers); err != nil {
+		return nil, err
+	}
+	return jm, nil
+}
+
+func jsonLayers(layers []Layer) ([]*jsonLayer, error) {
+	jls := []*jsonLayer{}
+	for _, l := range layers {
+		var (
+			jl  *jsonLayer
+			err error
+		)
+		switch l := l.(type) {
+		case *TileLayer:
+			jl, err = jsonTileLayer(l)
+		case *ObjectLayer:
+			jl, err = jsonObjectLayer(l)
+		case *ImageLayer:
+			jl, err = jsonImageLayer(l)
+		case *Group:
+			jl, err = jsonGroup(l)
+		}
+		if err != nil {
+			return nil, err
+		}
+		jls = append(jls, jl)
+	}
+	return jls, nil
+}
+
+func jsonTileLayer(l *TileLayer) (*jsonLayer, error) {
+	props, err := jsonProperties(l.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	jl := &jsonLayer{
+		ID:         l.ID,
+		Name:       l.Name,
+		Class:      l.Class,
+		Type:       "tilelayer",
+		X:          l.X,
+		Y:          l.Y,
+		Width:      l.Width,
+		Height:     l.Height,
+		Opacity:    l.Opacity,
+		Visible:    l.Visible,
+		Locked:     l.Locked,
+		TintColor:  l.TintColor,
+		OffsetX:    l.OffsetX,
+		OffsetY:    l.OffsetY,
+		ParallaxX:  float32(l.ParallaxX),
+		ParallaxY:  float32(l.ParallaxY),
+		Properties: props,
+	}
+
+	gids := l.GlobalIDs()
+	if l.RawData == nil || l.RawData.Encoding != "base64" {
+		jl.Data = gids
+		return jl, nil
+	}
+
+	jl.Encoding = "base64"
+	jl.Compression = l.RawData.Compression
+	if jl.Compression == "lz4" {
+		// There is no lz4 writer to hand, so lz4 layers are written uncompressed
+		jl.Compression = ""
+	}
+	if jl.Data, err = encodeBase64(gids, jl.Compression); err != nil {
+		return nil, fmt.Errorf("layer %q: %w", l.Name, err)
+	}
+	return jl, nil
+}
+
+func encodeBase64(gids []GlobalID, compression string) (string, error) {
+	var buf bytes.Buffer
+	enc := base64.NewEncoder(base64.StdEncoding, &buf)
+
+	var w io.WriteCloser
+	switch compression {
+	case "":
+		w = enc
+	case "zlib":
+		w = zlib.NewWriter(enc)
+	case "gzip":
+		w = gzip.NewWriter(enc)
+	case "deflate":
+		fw, err := flate.NewWriter(enc, flate.DefaultCompression)
+		if err != nil {
+			return "", err
+		}
+		w = fw
+	case "zstd":
+		zw, err := zstd.NewWriter(enc)
+		if err != nil {
+			return "", err
+		}
+		w = zw
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedCompression, compression)
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, gids); err != nil {
+		return "", err
+	}
+	if w != enc {
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func jsonObjectLayer(l *ObjectLayer) (*jsonLayer, error) {
+	props, err := jsonProperties(l.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	drawOrder := l.DrawOrder
+	jl := &jsonLayer{
+		ID:         l.ID,
+		Name:       l.Name,
+		Class:      l.Class,
+		Type:       "objectgroup",
+		X:          l.X,
+		Y:          l.Y,
+		Opacity:    l.Opacity,
+		Visible:    l.Visible,
+		Locked:     l.Locked,
+		TintColor:  l.TintColor,
+		OffsetX:    l.OffsetX,
+		OffsetY:    l.OffsetY,
+		ParallaxX:  l.ParallaxX,
+		ParallaxY:  l.ParallaxY,
+		Properties: props,
+		Color:      l.Color,
+		DrawOrder:  &drawOrder,
+		Objects:    []*jsonObject{},
+	}
+
+	if l.Objects == nil {
+		return jl, nil
+	}
+	for _, o := range *l.Objects {
+		jo, err := jsonObjectOf(o)
+		if err != nil {
+			return nil, err
+		}
+		jl.Objects = append(jl.Objects, jo)
+	}
+	return jl, nil
+}
+
+func jsonObjectOf(o *Object) (*jsonObject, error) {
+	props, err := jsonProperties(o.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	// The JSON format stores the class of an Object in its type
+	class := o.Class
+	if class == "" {
+		class = o.Type
+	}
+
+	visible := o.Visible
+	jo := &jsonObject{
+		ID:         o.ObjectID,
+		Name:       o.Name,
+		Type:       class,
+		X:          o.X,
+		Y:          o.Y,
+		Width:      o.Width,
+		Height:     o.Height,
+		Rotation:   o.Rotation,
+		Visible:    &visible,
+		GlobalID:   o.GlobalID,
+		Template:   o.Template,
+		Point:      o.IsPoint(),
+		Ellipse:    o.IsEllipse(),
+		Properties: props,
+	}
+
+	if o.IsPolygon() {
+		if jo.Polygon, err = o.Polygon.PointsF(); err != nil {
+			return nil, err
+		}
+	}
+	if o.IsPolyline() {
+		if jo.Polyline, err = o.Polyline.PointsF(); err != nil {
+			return nil, err
+		}
+	}
+	if o.IsText() {
+		jo.Text = &jsonText{
+			Text:       o.Text.Value,
+			FontFamily: o.Text.FontFamily,
+			PixelSize:  o.Text.PixelSize,
+			Wrap:       o.Text.Wrap,
+			Color:      o.Text.Color,
+			Bold:       o.Text.Bold,
+			Italic:     o.Text.Italic,
+			Underline:  o.Text.Underline,
+			Strikeout:  o.Text.Strikeout,
+			Kerning:    o.Text.Kerning,
+			HAlign:     o.Text.HAlign,
+			VAlign:     o.Text.VAlign,
+		}
+	}
+	return jo, nil
+}
+
+func jsonImageLayer(l *ImageLayer) (*jsonLayer, error) {
+	props, err := jsonProperties(l.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	jl := &jsonLayer{
+		ID:         l.ID,
+		Name:       l.Name,
+		Class:      l.Class,
+		Type:       "imagelayer",
+		X:          float32(l.X),
+		Y:          float32(l.Y),
+		Opacity:    l.Opacity,
+		Visible:    l.Visible,
+		Locked:     l.Locked,
+		TintColor:  l.TintColor,
+		OffsetX:    l.OffsetX,
+		OffsetY:    l.OffsetY,
+		ParallaxX:  float32(l.ParallaxX),
+		ParallaxY:  float32(l.ParallaxY),
+		Properties: props,
+		RepeatX:    l.RepeatX,
+		RepeatY:    l.RepeatY,
+	}
+	if l.Image != nil {
+		jl.Image = l.Image.Source
+		jl.ImageWidth = l.Image.Width
+		jl.ImageHeight = l.Image.Height
+		jl.TransparentColor = jsonColor(l.Image.TransparentColor)
+	}
+	return jl, nil
+}
+
+func jsonGroup(g *Group) (*jsonLayer, error) {
+	props, err := jsonProperties(g.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := jsonLayers(g.Layers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jsonLayer{
+		ID:         g.ID,
+		Name:       g.Name,
+		Class:      g.Class,
+		Type:       "group",
+		Opacity:    g.Opacity,
+		Visible:    g.Visible,
+		Locked:     g.Locked,
+		TintColor:  g.TintColor,
+		OffsetX:    g.OffsetX,
+		OffsetY:    g.OffsetY,
+		ParallaxX:  float32(g.ParallaxX),
+		ParallaxY:  float32(g.ParallaxY),
+		Properties: props,
+		Layers:     layers,
+	}, nil
+}
+
+func jsonTilesetOf(ts *Tileset) (*jsonTileset, error) {
+	if ts.Source != "" {
+		return &jsonTileset{FirstGlobalID: ts.FirstGlobalID, Source: ts.Source}, nil
+	}
+
+	props, err := jsonProperties(ts.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	jts := &jsonTileset{
+		FirstGlobalID:   ts.FirstGlobalID,
+		Name:            ts.Name,
+		Class:           ts.Class,
+		TileWidth:       ts.TileWidth,
+		TileHeight:      ts.TileHeight,
+		Spacing:         ts.Spacing,
+		Margin:          ts.Margin,
+		TileCount:       ts.TileCount,
+		Columns:         ts.Columns,
+		TileOffset:      ts.TileOffset,
+		Transformations: ts.Transformations,
+		Properties:      props,
+	}
+	if ts.ObjectAlignment != Unspecified {
+		oa := ts.ObjectAlignment
+		jts.ObjectAlignment = &oa
+	}
+	if ts.TileRenderSize != RenderSizeTile {
+		trs := ts.TileRenderSize
+		jts.TileRenderSize = &trs
+	}
+	if ts.FillMode != Stretch {
+		fm := ts.FillMode
+		jts.FillMode = &fm
+	}
+
+	// Collection tilesets borrow the image of one of their Tiles when decoded; only write an image of their own
+	if !ts.isCollection() {
+		jts.Image = ts.Image.Source
+		jts.ImageWidth = ts.Image.Width
+		jts.ImageHeight = ts.Image.Height
+		jts.TransparentColor = jsonColor(ts.Image.TransparentColor)
+	}
+
+	if ts.TerrainTypes != nil {
+		for _, tt := range *ts.TerrainTypes {
+			props, err := jsonProperties(tt.Properties)
+			if err != nil {
+				return nil, err
+			}
+			jts.Terrains = append(jts.Terrains, &jsonTerrain{Name: tt.Name, Tile: tt.TileID, Properties: props})
+		}
+	}
+	if ts.WangSets != nil {
+		for _, ws := range *ts.WangSets {
+			jws, err := jsonWangSetOf(ws)
+			if err != nil {
+				return nil, err
+			}
+			jts.WangSets = append(jts.WangSets, jws)
+		}
+	}
+
+	if !ts.HasTiles() {
+		return jts, nil
+	}
+	for _, tile := range *ts.Tiles {
+		jt, err := jsonTileOf(tile)
+		if err != nil {
+			return nil, err
+		}
+		jts.Tiles = append(jts.Tiles, jt)
+	}
+	return jts, nil
+}
+
+func jsonWangSetOf(ws *WangSet) (*jsonWangSet, error) {
+	props, err := jsonProperties(ws.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	jws := &jsonWangSet{
+		Name:       ws.Name,
+		Class:      ws.Class,
+		Type:       ws.Type,
+		Tile:       ws.TileID,
+		Properties: props,
+		Colors:     []*jsonWangColor{},
+		WangTiles:  []*jsonWangTile{},
+	}
+	if ws.WangColors != nil {
+		for _, wc := range *ws.WangColors {
+			props, err := jsonProperties(wc.Properties)
+			if err != nil {
+				return nil, err
+			}
+			jws.Colors = append(jws.Colors, &jsonWangColor{
+				Name:       wc.Name,
+				Class:      wc.Class,
+				Color:      wc.Color,
+				Tile:       wc.TileID,
+				Properties: props,
+			})
+		}
+	}
+	if ws.WangTiles != nil {
+		for _, wt := range *ws.WangTiles {
+			// The JSON format has no legacy 0xCECECECE form, so every WangID is written as its colors
+			colors, err := wt.WangID.Colors()
+			if err != nil {
+				return nil, err
+			}
+			jws.WangTiles = append(jws.WangTiles, &jsonWangTile{TileID: wt.TileID, WangID: colors[:]})
+		}
+	}
+	return jws, nil
+}
+
+func jsonTileOf(tile *Tile) (*jsonTile, error) {
+	props, err := jsonProperties(tile.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	jt := &jsonTile{
+		ID:          tile.TileID,
+		Type:        tile.Type,
+		Probability: tile.Probability,
+		X:           tile.X,
+		Y:           tile.Y,
+		Width:       tile.Width,
+		Height:      tile.Height,
+		Properties:  props,
+	}
+	if tile.HasImage() {
+		jt.Image = tile.Image.Source
+		jt.ImageWidth = tile.Image.Width
+		jt.ImageHeight = tile.Image.Height
+	}
+	if tile.RawTerrainType != "" {
+		// Corners without a terrain are left empty in the TMX format and written as -1
+		for _, s := range strings.Split(tile.RawTerrainType, ",") {
+			terrain := -1
+			if s = strings.TrimSpace(s); s != "" {
+				if terrain, err = strconv.Atoi(s); err != nil {
+					return nil, fmt.Errorf("tile %d terrain: %w", tile.TileID, err)
+				}
+			}
+			jt.Terrain = append(jt.Terrain, terrain)
+		}
+	}
+	if tile.HasAnimation() {
+		for _, f := range *tile.Animation {
+			jt.Animation = append(jt.Animation, &jsonFrame{TileID: f.TileID, Duration: f.DurationMsec})
+		}
+	}
+	if tile.HasObjectLayer() {
+		if jt.ObjectGroup, err = jsonObjectLayer(tile.ObjectLayer); err != nil {
+			return nil, err
+		}
+	}
+	return jt, nil
+}
+
+func jsonProperties(pl *Properties) ([]*jsonProperty, error) {
+	if pl == nil {
+		return nil, nil
+	}
+
+	jps := make([]*jsonProperty, 0, len(*pl))
+	for _, p := range *pl {
+		v, err := jsonPropertyValue(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w: property %q: %w", ErrPropertyFailedConversion, p.Name, err)
+		}
+		jps = append(jps, &jsonProperty{Name: p.Name, Type: p.Type, PropertyType: p.CustomType, Value: v})
+	}
+	return jps, nil
+}
+
+// jsonPropertyValue converts the string value of a Property to the JSON type matching its PropertyType
+func jsonPropertyValue(p *Property) (any, error) {
+	s := p.Value
+	if s == "" {
+		s = strings.TrimSpace(p.InnerValue)
+	}
+
+	switch p.Type {
+	case Int, Obj:
+		if s == "" {
+			return 0, nil
+		}
+		return strconv.ParseInt(s, 10, 64)
+	case Float:
+		if s == "" {
+			return 0, nil
+		}
+		return strconv.ParseFloat(s, 64)
+	case Bool:
+		if s == "" {
+			return false, nil
+		}
+		return strconv.ParseBool(s)
+	case Class:
+		members := map[string]any{}
+		if p.Properties == nil {
+			return members, nil
+		}
+		for _, np := range *p.Properties {
+			v, err := jsonPropertyValue(np)
+			if err != nil {
+				return nil, err
+			}
+			members[np.Name] = v
+		}
+		return members, nil
+	default:
+		return s, nil
+	}
+}
+
+// jsonColor returns a color from a TMX `trans` attribute, which has no leading '#', in the '#RRGGBB' form of the JSON
+// format
+func jsonColor(c string) string {
+	if c == "" || strings.HasPrefix(c, "#") {
+		return c
+	}
+	return "#" + c
+}
+
+// UnmarshalJSON decodes a Map from the Tiled JSON map format (.tmj) https://doc.mapeditor.org/en/stable/reference/json-map-format/
+// into the same Map the TMX format decodes to, honouring the Options of the load in progress. External Tilesets and
+// templates are loaded from their TMX formats (.tsx and .tx); the JSON tileset and template formats are not read.
+// Like UnmarshalXML, it decodes with the zero Options; New decodes through decodeJSON with the Options of the load.
+func (t *Map) UnmarshalJSON(data []byte) error {
+	defer lockDecode()()
+	return t.decodeJSON(data)
+}
+
+// decodeJSON decodes a JSON map document; callers must hold decodeMu
+func (t *Map) decodeJSON(data []byte) error {
+	decodeWarnings = nil
+	defer func() {
+		t.warnings, decodeWarnings = decodeWarnings, nil
+	}()
+
+	var jm jsonMap
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingTilemap, err)
+	}
+	if jm.Type != "map" {
+		return fmt.Errorf("%w: type %q is not a map", ErrDecodingTilemap, jm.Type)
+	}
+
+	m, err := jm.toMap()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingTilemap, err)
+	}
+
+	*t = *m
+	return t.finishDecode()
+}
+
+func (jm *jsonMap) toMap() (*Map, error) {
+	t := &Map{
+		Version:         jm.Version,
+		TiledVersion:    jm.TiledVersion,
+		Class:           jm.Class,
+		Orientation:     jm.Orientation,
+		RenderOrder:     jm.RenderOrder,
+		Width:           jm.Width,
+		Height:          jm.Height,
+		TileWidth:       jm.TileWidth,
+		TileHeight:      jm.TileHeight,
+		HexSideLength:   jm.HexSideLength,
+		StaggerAxis:     jm.StaggerAxis,
+		StaggerIndex:    jm.StaggerIndex,
+		BackgroundColor: jm.BackgroundColor,
+		ParallaxOriginX: jm.ParallaxOriginX,
+		ParallaxOriginY: jm.ParallaxOriginY,
+		NextLayerID:     jm.NextLayerID,
+		NextObjectID:    jm.NextObjectID,
+		Infinite:        jm.Infinite,
+		EditorSettings:  jm.EditorSettings,
+	}
+
+	var err error
+	if t.Properties, err = propertiesOf(jm.Properties); err != nil {
+		return nil, err
+	}
+
+	if len(jm.Tilesets) > 0 {
+		tilesets := make(Tilesets, 0, len(jm.Tilesets))
+		for _, jts := range jm.Tilesets {
+			ts, err := jts.toTileset()
+			if err != nil {
+				return nil, err
+			}
+			tilesets = append(tilesets, ts)
+		}
+		t.Tilesets = &tilesets
+	}
+
+	if t.Layers, err = layersOf(jm.Layers); err != nil {
+		return nil, err
+	}
+	t.TileLayers, t.ObjectLayers, t.ImageLayers, t.Groups = splitLayers(t.Layers)
+	return t, nil
+}
+
+// layersOf decodes JSON layers, which are listed in draw order, to Layers
+func layersOf(jls []*jsonLayer) ([]Layer, error) {
+	var layers []Layer
+	for _, jl := range jls {
+		var (
+			l   Layer
+			err error
+		)
+		switch jl.Type {
+		case "tilelayer":
+			l, err = jl.toTileLayer()
+		case "objectgroup":
+			l, err = jl.toObjectLayer()
+		case "imagelayer":
+			l, err = jl.toImageLayer()
+		case "group":
+			l, err = jl.toGroup()
+		default:
+			return nil, fmt.Errorf("layer %q has unknown type %q", jl.Name, jl.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, l)
+	}
+	return layers, nil
+}
+
+// splitLayers sorts Layers into the typed slices the TMX format decodes them to, leaving a slice `nil` when it would be
+// empty
+func splitLayers(layers []Layer) (*TileLayers, *ObjectLayers, *ImageLayers, *Groups) {
+	var (
+		tls TileLayers
+		ols ObjectLayers
+		ils ImageLayers
+		gs  Groups
+	)
+	for _, l := range layers {
+		switch l := l.(type) {
+		case *TileLayer:
+			tls = append(tls, l)
+		case *ObjectLayer:
+			ols = append(ols, l)
+		case *ImageLayer:
+			ils = append(ils, l)
+		case *Group:
+			gs = append(gs, l)
+		}
+	}
+
+	var (
+		ptls *TileLayers
+		pols *ObjectLayers
+		pils *ImageLayers
+		pgs  *Groups
+	)
+	if tls != nil {
+		ptls = &tls
+	}
+	if ols != nil {
+		pols = &ols
+	}
+	if ils != nil {
+		pils = &ils
+	}
+	if gs != nil {
+		pgs = &gs
+	}
+	return ptls, pols, pils, pgs
+}
+
+func (jl *jsonLayer) UnmarshalJSON(data []byte) error {
+	type tmpLayer jsonLayer
+	// Like Tiled, layers are visible and fully opaque unless the document says otherwise
+	tmp := tmpLayer{Opacity: 1, Visible: true}
+
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+
+	*jl = (jsonLayer)(tmp)
+	return nil
+}
+
+func (jl *jsonLayer) toTileLayer() (*TileLayer, error) {
+	props, err := propertiesOf(jl.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &TileLayer{
+		ID:         jl.ID,
+		Name:       jl.Name,
+		Class:      jl.Class,
+		X:          jl.X,
+		Y:          jl.Y,
+		Width:      jl.Width,
+		Height:     jl.Height,
+		Opacity:    jl.Opacity,
+		Visible:    jl.Visible,
+		Locked:     jl.Locked,
+		TintColor:  jl.TintColor,
+		OffsetX:    jl.OffsetX,
+		OffsetY:    jl.OffsetY,
+		ParallaxX:  int(jl.ParallaxX),
+		ParallaxY:  int(jl.ParallaxY),
+		Properties: props,
+	}
+
+	switch data := jl.Data.(type) {
+	case nil:
+	case string:
+		l.RawData = &Data{Encoding: jl.Encoding, Compression: jl.Compression, RawBytes: []byte(data)}
+	case []any:
+		if l.RawData, err = csvData(data, jl.Width); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrDecodingTileLayerData, err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: tile layer %q data is neither an array nor a string", ErrDecodingTileLayerData,
+			jl.Name)
+	}
+
+	if err := l.finishDecode(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// csvData holds the GlobalIDs of a JSON data array as the CSV the TMX format stores them as, a row of width GlobalIDs
+// per line
+func csvData(gids []any, width int) (*Data, error) {
+	var b []byte
+	for i, v := range gids {
+		gid, ok := v.(float64)
+		if !ok || gid < 0 || gid > math.MaxUint32 || gid != math.Trunc(gid) {
+			return nil, fmt.Errorf("invalid global ID %v", v)
+		}
+		if i > 0 {
+			b = append(b, ',')
+			if width > 0 && i%width == 0 {
+				b = append(b, '\n')
+			}
+		}
+		b = strconv.AppendUint(b, uint64(gid), 10)
+	}
+	return &Data{Encoding: "csv", RawBytes: b}, nil
+}
+
+func (jl *jsonLayer) toObjectLayer() (*ObjectLayer, error) {
+	props, err := propertiesOf(jl.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &ObjectLayer{
+		ID:         jl.ID,
+		Name:       jl.Name,
+		Class:      jl.Class,
+		Color:      jl.Color,
+		X:          jl.X,
+		Y:          jl.Y,
+		Opacity:    jl.Opacity,
+		Visible:    jl.Visible,
+		Locked:     jl.Locked,
+		OffsetX:    jl.OffsetX,
+		OffsetY:    jl.OffsetY,
+		ParallaxX:  jl.ParallaxX,
+		ParallaxY:  jl.ParallaxY,
+		TintColor:  jl.TintColor,
+		Properties: props,
+	}
+	if jl.DrawOrder != nil {
+		l.DrawOrder = *jl.DrawOrder
+	}
+
+	if jl.Objects == nil {
+		return l, nil
+	}
+	objects := make(Objects, 0, len(jl.Objects))
+	for _, jo := range jl.Objects {
+		o, err := jo.toObject()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, o)
+	}
+	l.Objects = &objects
+	return l, nil
+}
+
+func (jo *jsonObject) toObject() (*Object, error) {
+	props, err := propertiesOf(jo.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &Object{
+		ObjectID:   jo.ID,
+		Name:       jo.Name,
+		Type:       jo.Type,
+		X:          jo.X,
+		Y:          jo.Y,
+		Width:      jo.Width,
+		Height:     jo.Height,
+		Rotation:   jo.Rotation,
+		Visible:    jo.Visible == nil || *jo.Visible,
+		Template:   jo.Template,
+		GlobalID:   jo.GlobalID,
+		Properties: props,
+		Polygon:    polyOf(jo.Polygon),
+		Polyline:   polyOf(jo.Polyline),
+	}
+	if jo.Point {
+		o.Point = &struct{}{}
+	}
+	if jo.Ellipse {
+		o.Ellipse = &struct{}{}
+	}
+	if jt := jo.Text; jt != nil {
+		o.Text = &Text{
+			FontFamily: jt.FontFamily,
+			PixelSize:  jt.PixelSize,
+			Wrap:       jt.Wrap,
+			Color:      jt.Color,
+			Bold:       jt.Bold,
+			Italic:     jt.Italic,
+			Underline:  jt.Underline,
+			Strikeout:  jt.Strikeout,
+			Kerning:    jt.Kerning,
+			HAlign:     jt.HAlign,
+			VAlign:     jt.VAlign,
+			Value:      jt.Text,
+		}
+	}
+
+	if err := o.finishDecode(jo.Visible != nil); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// polyOf formats JSON polygon or polyline points as the points attribute of the TMX format
+func polyOf(points []PointF) *Poly {
+	if points == nil {
+		return nil
+	}
+	pairs := make([]string, len(points))
+	for i, p := range points {
+		pairs[i] = strconv.FormatFloat(float64(p.X), 'f', -1, 32) + "," + strconv.FormatFloat(float64(p.Y), 'f', -1, 32)
+	}
+	return &Poly{RawPoints: strings.Join(pairs, " ")}
+}
+
+func (jt *jsonText) UnmarshalJSON(data []byte) error {
+	type tmpText jsonText
+	// Keys omitted from the document take Tiled's defaults; the zero HAlign and VAlign are left and top
+	tmp := tmpText{FontFamily: "sans-serif", PixelSize: 16, Color: "#000000", Kerning: true}
+
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		return err
+	}
+
+	*jt = (jsonText)(tmp)
+	return nil
+}
+
+func (jl *jsonLayer) toImageLayer() (*ImageLayer, error) {
+	props, err := propertiesOf(jl.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &ImageLayer{
+		ID:         jl.ID,
+		Name:       jl.Name,
+		Class:      jl.Class,
+		X:          int(jl.X),
+		Y:          int(jl.Y),
+		OffsetX:    jl.OffsetX,
+		OffsetY:    jl.OffsetY,
+		ParallaxX:  int(jl.ParallaxX),
+		ParallaxY:  int(jl.ParallaxY),
+		Opacity:    jl.Opacity,
+		Visible:    jl.Visible,
+		Locked:     jl.Locked,
+		TintColor:  jl.TintColor,
+		RepeatX:    jl.RepeatX,
+		RepeatY:    jl.RepeatY,
+		Properties: props,
+	}
+	if jl.Image != "" {
+		l.Image = &Image{
+			Source:           jl.Image,
+			TransparentColor: strings.TrimPrefix(jl.TransparentColor, "#"),
+			Width:            jl.ImageWidth,
+			Height:           jl.ImageHeight,
+		}
+	}
+	return l, nil
+}
+
+func (jl *jsonLayer) toGroup() (*Group, error) {
+	props, err := propertiesOf(jl.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Group{
+		ID:         jl.ID,
+		Name:       jl.Name,
+		Class:      jl.Class,
+		Opacity:    jl.Opacity,
+		Visible:    jl.Visible,
+		Locked:     jl.Locked,
+		OffsetX:    jl.OffsetX,
+		OffsetY:    jl.OffsetY,
+		ParallaxX:  int(jl.ParallaxX),
+		ParallaxY:  int(jl.ParallaxY),
+		TintColor:  jl.TintColor,
+		Properties: props,
+	}
+	if g.Layers, err = layersOf(jl.Layers); err != nil {
+		return nil, err
+	}
+	g.TileLayers, g.ObjectLayers, g.ImageLayers, g.Groups = splitLayers(g.Layers)
+	g.adopt()
+	return g, nil
+}
+
+func (jts *jsonTileset) toTileset() (*Tileset, error) {
+	props, err := propertiesOf(jts.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &Tileset{
+		FirstGlobalID:   jts.FirstGlobalID,
+		Source:          jts.Source,
+		Name:            jts.Name,
+		Class:           jts.Class,
+		TileWidth:       jts.TileWidth,
+		TileHeight:      jts.TileHeight,
+		Spacing:         jts.Spacing,
+		Margin:          jts.Margin,
+		TileCount:       jts.TileCount,
+		Columns:         jts.Columns,
+		Properties:      props,
+		TileOffset:      jts.TileOffset,
+		Transformations: jts.Transformations,
+	}
+	if jts.ObjectAlignment != nil {
+		ts.ObjectAlignment = *jts.ObjectAlignment
+	}
+	if jts.TileRenderSize != nil {
+		ts.TileRenderSize = *jts.TileRenderSize
+	}
+	if jts.FillMode != nil {
+		ts.FillMode = *jts.FillMode
+	}
+	if jts.Image != "" {
+		ts.Image = &Image{
+			Source:           jts.Image,
+			TransparentColor: strings.TrimPrefix(jts.TransparentColor, "#"),
+			Width:            jts.ImageWidth,
+			Height:           jts.ImageHeight,
+		}
+	}
+
+	if jts.Terrains != nil {
+		terrains := make([]*Terrain, 0, len(jts.Terrains))
+		for _, jt := range jts.Terrains {
+			props, err := propertiesOf(jt.Properties)
+			if err != nil {
+				return nil, err
+			}
+			terrains = append(terrains, &Terrain{Name: jt.Name, TileID: jt.Tile, Properties: props})
+		}
+		ts.TerrainTypes = &terrains
+	}
+	if jts.WangSets != nil {
+		wangSets := make(WangSets, 0, len(jts.WangSets))
+		for _, jws := range jts.WangSets {
+			ws, err := jws.toWangSet()
+			if err != nil {
+				return nil, err
+			}
+			wangSets = append(wangSets, ws)
+		}
+		ts.WangSets = &wangSets
+	}
+	if jts.Tiles != nil {
+		tiles := make(Tiles, 0, len(jts.Tiles))
+		for _, jt := range jts.Tiles {
+			tile, err := jt.toTile()
+			if err != nil {
+				return nil, err
+			}
+			tiles = append(tiles, tile)
+		}
+		ts.Tiles = &tiles
+	}
+
+	if err := ts.finishDecode(); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+func (jws *jsonWangSet) toWangSet() (*WangSet, error) {
+	props, err := propertiesOf(jws.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &WangSet{Name: jws.Name, Class: jws.Class, Type: jws.Type, TileID: jws.Tile, Properties: props}
+	if jws.Colors != nil {
+		colors := make([]*WangColor, 0, len(jws.Colors))
+		for _, jwc := range jws.Colors {
+			props, err := propertiesOf(jwc.Properties)
+			if err != nil {
+				return nil, err
+			}
+			colors = append(colors, &WangColor{
+				Name:       jwc.Name,
+				Class:      jwc.Class,
+				Color:      jwc.Color,
+				TileID:     jwc.Tile,
+				Properties: props,
+			})
+		}
+		ws.WangColors = &colors
+	}
+	if jws.WangTiles != nil {
+		wangTiles := make([]*WangTile, 0, len(jws.WangTiles))
+		for _, jwt := range jws.WangTiles {
+			colors := make([]string, len(jwt.WangID))
+			for i, c := range jwt.WangID {
+				colors[i] = strconv.Itoa(c)
+			}
+			wangTiles = append(wangTiles, &WangTile{TileID: jwt.TileID, WangID: WangID(strings.Join(colors, ","))})
+		}
+		ws.WangTiles = &wangTiles
+	}
+	return ws, nil
+}
+
+func (jt *jsonTile) toTile() (*Tile, error) {
+	props, err := propertiesOf(jt.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	tile := &Tile{
+		TileID:      jt.ID,
+		X:           jt.X,
+		Y:           jt.Y,
+		Width:       jt.Width,
+		Height:      jt.Height,
+		Probability: jt.Probability,
+		Type:        jt.Type,
+		Properties:  props,
+	}
+	if jt.Image != "" {
+		tile.Image = &Image{Source: jt.Image, Width: jt.ImageWidth, Height: jt.ImageHeight}
+	}
+	if jt.Animation != nil {
+		animation := make(Animation, 0, len(jt.Animation))
+		for _, f := range jt.Animation {
+			animation = append(animation, &Frame{TileID: f.TileID, DurationMsec: f.Duration})
+		}
+		tile.Animation = &animation
+	}
+	if jt.ObjectGroup != nil {
+		if tile.ObjectLayer, err = jt.ObjectGroup.toObjectLayer(); err != nil {
+			return nil, err
+		}
+	}
+	if jt.Terrain != nil {
+		// Corners without a terrain are -1 in the JSON format and left empty in the TMX format
+		corners := make([]string, len(jt.Terrain))
+		for i, terrain := range jt.Terrain {
+			if terrain >= 0 {
+				corners[i] = strconv.Itoa(terrain)
+			}
+		}
+		tile.RawTerrainType = strings.Join(corners, ",")
+	}
+
+	if err := tile.finishDecode(); err != nil {
+		return nil, err
+	}
+	return tile, nil
+}
+
+// propertiesOf converts JSON Properties to Properties as the TMX format decodes them
+func propertiesOf(jps []*jsonProperty) (*Properties, error) {
+	if jps == nil {
+		return nil, nil
+	}
+
+	pl := make(Properties, 0, len(jps))
+	for _, jp := range jps {
+		p, err := jp.toProperty()
+		if err != nil {
+			return nil, fmt.Errorf("%w: property %q: %w", ErrDecodingProperty, jp.Name, err)
+		}
+		pl = append(pl, p)
+	}
+	return &pl, nil
+}
+
+func (jp *jsonProperty) toProperty() (*Property, error) {
+	p := &Property{Name: jp.Name, Type: jp.Type, CustomType: jp.PropertyType}
+	if jp.Type != Class {
+		v, err := scalarString(jp.Value)
+		if err != nil {
+			return nil, err
+		}
+		p.Value, p.InnerValue = v, v
+		return p, nil
+	}
+
+	var members map[string]any
+	if jp.Value != nil {
+		var ok bool
+		if members, ok = jp.Value.(map[string]any); !ok {
+			return nil, fmt.Errorf("class value %v is not an object", jp.Value)
+		}
+	}
+
+	var err error
+	p.Properties, err = classMembers(jp.PropertyType, members)
+	return p, err
+}
+
+// classMembers converts the members of a JSON class value to Properties. The JSON format doesn't record the type of
+// members, so they are taken from the class declared in the Project the Map is loaded against, or else inferred from
+// the JSON values; numbers without a fraction are taken to be ints.
+func classMembers(class string, values map[string]any) (*Properties, error) {
+	var decl *CustomType
+	if decodeOptions.project != nil {
+		decl = decodeOptions.project.ClassWithName(class)
+	}
+
+	pl := make(Properties, 0, len(values))
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		v := values[name]
+		jp := &jsonProperty{Name: name, Type: memberType(v), Value: v}
+
+		var member *ClassMember
+		if decl != nil {
+			if i := slices.IndexFunc(decl.Members, func(m *ClassMember) bool { return m.Name == name }); i >= 0 {
+				member = decl.Members[i]
+			}
+		}
+		if member != nil {
+			if err := jp.Type.UnmarshalText([]byte(member.Type)); err != nil {
+				return nil, err
+			}
+			jp.PropertyType = member.PropertyType
+		}
+
+		p, err := jp.toProperty()
+		if err != nil {
+			return nil, fmt.Errorf("member %q: %w", name, err)
+		}
+		pl = append(pl, p)
+	}
+	return &pl, nil
+}
+
+// memberType infers the PropertyType of a class member from its JSON value
+func memberType(v any) PropertyType {
+	switch v := v.(type) {
+	case bool:
+		return Bool
+	case float64:
+		if v == math.Trunc(v) {
+			return Int
+		}
+		return Float
+	case map[string]any:
+		return Class
+	default:
+		return String
+	}
+}
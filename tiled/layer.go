@@ -0,0 +1,69 @@
+package tiled
+
+import "sort"
+
+// LayerID is a per-map unique layer ID; see Map.NextLayerID
+type LayerID uint32
+
+// Layer is implemented by TileLayer, ObjectLayer, ImageLayer and Group, allowing them to be held together in the order
+// they were declared.
+type Layer interface {
+	LayerName() string
+	LayerVisible() bool
+	LayerOpacity() float32
+
+	documentOffset() int64
+}
+
+func (l *TileLayer) LayerName() string       { return l.Name }
+func (l *TileLayer) LayerVisible() bool      { return l.Visible }
+func (l *TileLayer) LayerOpacity() float32   { return l.Opacity }
+func (l *TileLayer) documentOffset() int64   { return l.offset }
+func (l *ObjectLayer) LayerName() string     { return l.Name }
+func (l *ObjectLayer) LayerVisible() bool    { return l.Visible }
+func (l *ObjectLayer) LayerOpacity() float32 { return l.Opacity }
+func (l *ObjectLayer) documentOffset() int64 { return l.offset }
+func (l *ImageLayer) LayerName() string      { return l.Name }
+func (l *ImageLayer) LayerVisible() bool     { return l.Visible }
+func (l *ImageLayer) LayerOpacity() float32  { return l.Opacity }
+func (l *ImageLayer) documentOffset() int64  { return l.offset }
+func (g *Group) LayerName() string           { return g.Name }
+func (g *Group) LayerVisible() bool          { return g.Visible }
+func (g *Group) LayerOpacity() float32       { return g.Opacity }
+func (g *Group) documentOffset() int64       { return g.offset }
+
+type byDocumentOffset []Layer
+
+func (a byDocumentOffset) Len() int           { return len(a) }
+func (a byDocumentOffset) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byDocumentOffset) Less(i, j int) bool { return a[i].documentOffset() < a[j].documentOffset() }
+
+// documentOrder merges the typed layer slices back into the order they appeared in the document
+func documentOrder(tls *TileLayers, ols *ObjectLayers, ils *ImageLayers, gs *Groups) []Layer {
+	var layers []Layer
+
+	if tls != nil {
+		for _, l := range *tls {
+			layers = append(layers, l)
+		}
+	}
+	if ols != nil {
+		for _, l := range *ols {
+			layers = append(layers, l)
+		}
+	}
+	if ils != nil {
+		for _, l := range *ils {
+			layers = append(layers, l)
+		}
+	}
+	if gs != nil {
+		for _, g := range *gs {
+			layers = append(layers, g)
+		}
+	}
+
+	sort.Sort(byDocumentOffset(layers))
+
+	return layers
+}
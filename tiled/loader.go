@@ -0,0 +1,58 @@
+package tiled
+
+import (
+	"io"
+	"io/fs"
+	"path/filepath"
+)
+
+// Loader loads Maps, Tilesets and templates with a fixed set of Options, such as WithFS, WithOpener, Lenient,
+// HeaderOnly and SkipExternal; a Loader can be reused for any number of loads and shared between goroutines. A Loader
+// caches nothing, so every load opens the files it needs again.
+type Loader struct {
+	opts []Option
+}
+
+// NewLoader returns a Loader applying the given Options to every load
+func NewLoader(opts ...Option) *Loader {
+	return &Loader{opts: append([]Option(nil), opts...)}
+}
+
+// Load returns a Map from the given path; see New
+func (l *Loader) Load(path string) (*Map, error) {
+	return New(path, l.opts...)
+}
+
+// LoadTileset returns a Tileset from the given external tileset path; see LoadTileset
+func (l *Loader) LoadTileset(path string) (*Tileset, error) {
+	return LoadTileset(path, l.opts...)
+}
+
+// LoadTemplate returns a Template from the given object template path; see LoadTemplate
+func (l *Loader) LoadTemplate(path string) (*Template, error) {
+	return LoadTemplate(path, l.opts...)
+}
+
+// Lenient is LenientEnums(true) for Loaders
+func Lenient() Option {
+	return LenientEnums(true)
+}
+
+// SkipExternal is SkipExternalResolve(true) for Loaders
+func SkipExternal() Option {
+	return SkipExternalResolve(true)
+}
+
+// WithFS opens the map, Tilesets and templates from the file system fsys instead of the OS file system; paths are
+// converted to the slash separated form fs.FS expects
+func WithFS(fsys fs.FS) Option {
+	return WithOpener(fsOpener{fsys})
+}
+
+type fsOpener struct {
+	fsys fs.FS
+}
+
+func (o fsOpener) Open(name string) (io.ReadCloser, error) {
+	return o.fsys.Open(filepath.ToSlash(filepath.Clean(name)))
+}
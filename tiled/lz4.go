@@ -0,0 +1,242 @@
+package tiled
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// LZ4 frame format constants, see https://github.com/lz4/lz4/blob/dev/doc/lz4_Frame_format.md
+const (
+	lz4FrameMagic     = 0x184d2204
+	lz4SkippableMagic = 0x184d2a50 // The low 4 bits are free, so skippable frames span 0x184d2a50 to 0x184d2a5f
+	lz4Uncompressed   = 0x80000000 // Block size flag marking a block stored uncompressed
+
+	lz4FlagBlockChecksum   = 0x10
+	lz4FlagContentSize     = 0x08
+	lz4FlagContentChecksum = 0x04
+	lz4FlagDictID          = 0x01
+)
+
+var (
+	errCorruptLZ4  = errors.New("lz4: corrupt input")
+	errLZ4Checksum = errors.New("lz4: checksum mismatch")
+)
+
+// decodeLZ4Frames decompresses one or more concatenated LZ4 frames, skipping skippable frames. Blocks are decoded into
+// a single buffer, so both independent and linked blocks are supported; header, block and content checksums are verified.
+func decodeLZ4Frames(raw []byte) ([]byte, error) {
+	var dst []byte
+	for len(raw) > 0 {
+		if len(raw) < 4 {
+			return nil, errCorruptLZ4
+		}
+
+		switch magic := binary.LittleEndian.Uint32(raw); {
+		case magic&^0xf == lz4SkippableMagic:
+			if len(raw) < 8 {
+				return nil, errCorruptLZ4
+			}
+			size := uint64(binary.LittleEndian.Uint32(raw[4:]))
+			if uint64(len(raw)-8) < size {
+				return nil, errCorruptLZ4
+			}
+			raw = raw[8+size:]
+		case magic == lz4FrameMagic:
+			var err error
+			if dst, raw, err = decodeLZ4Frame(dst, raw[4:]); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("lz4: unknown frame magic %#x", magic)
+		}
+	}
+	return dst, nil
+}
+
+// decodeLZ4Frame appends the blocks of the LZ4 frame at the start of raw, following its magic number, to dst, returning
+// the data after the frame
+func decodeLZ4Frame(dst, raw []byte) ([]byte, []byte, error) {
+	if len(raw) < 3 {
+		return nil, nil, errCorruptLZ4
+	}
+	flags := raw[0]
+	if flags>>6 != 1 {
+		return nil, nil, fmt.Errorf("lz4: unsupported frame version %d", flags>>6)
+	}
+	if flags&lz4FlagDictID != 0 {
+		return nil, nil, errors.New("lz4: preset dictionaries are unsupported")
+	}
+
+	// The header checksum follows the flags, block descriptor and optional content size
+	header := 2
+	if flags&lz4FlagContentSize != 0 {
+		header += 8
+	}
+	if len(raw) < header+1 {
+		return nil, nil, errCorruptLZ4
+	}
+	if byte(xxh32(raw[:header])>>8) != raw[header] {
+		return nil, nil, errLZ4Checksum
+	}
+	raw = raw[header+1:]
+	start := len(dst)
+
+	for {
+		if len(raw) < 4 {
+			return nil, nil, errCorruptLZ4
+		}
+		size := binary.LittleEndian.Uint32(raw)
+		raw = raw[4:]
+		if size == 0 {
+			break
+		}
+
+		n := int(size &^ lz4Uncompressed)
+		if len(raw) < n {
+			return nil, nil, errCorruptLZ4
+		}
+		block := raw[:n]
+		raw = raw[n:]
+
+		// The block checksum covers the block as stored, before decompression
+		if flags&lz4FlagBlockChecksum != 0 {
+			if len(raw) < 4 {
+				return nil, nil, errCorruptLZ4
+			}
+			if xxh32(block) != binary.LittleEndian.Uint32(raw) {
+				return nil, nil, errLZ4Checksum
+			}
+			raw = raw[4:]
+		}
+
+		if size&lz4Uncompressed != 0 {
+			dst = append(dst, block...)
+		} else {
+			var err error
+			if dst, err = decodeLZ4Block(dst, block); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if flags&lz4FlagContentChecksum != 0 {
+		if len(raw) < 4 {
+			return nil, nil, errCorruptLZ4
+		}
+		if xxh32(dst[start:]) != binary.LittleEndian.Uint32(raw) {
+			return nil, nil, errLZ4Checksum
+		}
+		raw = raw[4:]
+	}
+	return dst, raw, nil
+}
+
+// decodeLZ4Block appends the decompressed LZ4 block src to dst; matches may reach back into the data already in dst
+func decodeLZ4Block(dst, src []byte) ([]byte, error) {
+	// length reads a 4 bit length from a sequence token, extended by the following bytes while it is at its maximum
+	length := func(n int, i *int) (int, error) {
+		if n < 15 {
+			return n, nil
+		}
+		for {
+			if *i >= len(src) {
+				return 0, errCorruptLZ4
+			}
+			b := src[*i]
+			*i++
+			n += int(b)
+			if b != 255 {
+				return n, nil
+			}
+		}
+	}
+
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		literals, err := length(int(token>>4), &i)
+		if err != nil {
+			return nil, err
+		}
+		if len(src)-i < literals {
+			return nil, errCorruptLZ4
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+
+		// The last sequence of a block holds only literals
+		if i == len(src) {
+			break
+		}
+
+		if len(src)-i < 2 {
+			return nil, errCorruptLZ4
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, errCorruptLZ4
+		}
+
+		match, err := length(int(token&0xf), &i)
+		if err != nil {
+			return nil, err
+		}
+		// Matches may overlap the data they produce, so they're copied a byte at a time
+		start := len(dst) - offset
+		for j := range match + 4 {
+			dst = append(dst, dst[start+j])
+		}
+	}
+	return dst, nil
+}
+
+// xxHash32 primes, see https://github.com/Cyan4973/xxHash/blob/dev/doc/xxhash_spec.md
+const (
+	xxhPrime1 uint32 = 2654435761
+	xxhPrime2 uint32 = 2246822519
+	xxhPrime3 uint32 = 3266489917
+	xxhPrime4 uint32 = 668265263
+	xxhPrime5 uint32 = 374761393
+)
+
+// xxh32 returns the xxHash32 of b with a zero seed, the checksum used by LZ4 frames
+func xxh32(b []byte) uint32 {
+	round := func(acc, lane uint32) uint32 {
+		return bits.RotateLeft32(acc+lane*xxhPrime2, 13) * xxhPrime1
+	}
+
+	var seed uint32
+	n := uint32(len(b))
+	var h uint32
+	if len(b) >= 16 {
+		v1, v2, v3, v4 := seed+xxhPrime1+xxhPrime2, seed+xxhPrime2, seed, seed-xxhPrime1
+		for ; len(b) >= 16; b = b[16:] {
+			v1 = round(v1, binary.LittleEndian.Uint32(b))
+			v2 = round(v2, binary.LittleEndian.Uint32(b[4:]))
+			v3 = round(v3, binary.LittleEndian.Uint32(b[8:]))
+			v4 = round(v4, binary.LittleEndian.Uint32(b[12:]))
+		}
+		h = bits.RotateLeft32(v1, 1) + bits.RotateLeft32(v2, 7) + bits.RotateLeft32(v3, 12) + bits.RotateLeft32(v4, 18)
+	} else {
+		h = seed + xxhPrime5
+	}
+	h += n
+
+	for ; len(b) >= 4; b = b[4:] {
+		h = bits.RotateLeft32(h+binary.LittleEndian.Uint32(b)*xxhPrime3, 17) * xxhPrime4
+	}
+	for _, c := range b {
+		h = bits.RotateLeft32(h+uint32(c)*xxhPrime5, 11) * xxhPrime1
+	}
+
+	h ^= h >> 15
+	h *= xxhPrime2
+	h ^= h >> 13
+	h *= xxhPrime3
+	h ^= h >> 16
+	return h
+}
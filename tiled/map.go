@@ -2,8 +2,12 @@ package tiled
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"sort"
+	"image/color"
+	"maps"
+	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -22,18 +26,239 @@ type Map struct {
 	StaggerAxis     string      `xml:"staggeraxis,attr,omitempty"`
 	StaggerIndex    string      `xml:"staggerindex,attr,omitempty"`
 	BackgroundColor string      `xml:"backgroundcolor,attr,omitempty"`
-	NextLayerID     int         `xml:"nextlayerid,attr"`
+	ParallaxOriginX float32     `xml:"parallaxoriginx,attr,omitempty"`
+	ParallaxOriginY float32     `xml:"parallaxoriginy,attr,omitempty"`
+	NextLayerID     LayerID     `xml:"nextlayerid,attr"`
 	NextObjectID    int         `xml:"nextobjectid,attr"`
 	Infinite        bool        `xml:"infinite,attr,omitempty"`
 
-	Properties   *Properties   `xml:"properties>property"`
-	Tilesets     *Tilesets     `xml:"tileset"`
-	TileLayers   *TileLayers   `xml:"layer"`
-	ObjectLayers *ObjectLayers `xml:"objectgroup"`
-	ImageLayers  *ImageLayers  `xml:"imagelayer"`
-	Groups       *Groups       `xml:"group"`
+	EditorSettings *EditorSettings `xml:"editorsettings"`
+	Properties     *Properties     `xml:"properties>property"`
+	Tilesets       *Tilesets       `xml:"tileset"`
+	TileLayers     *TileLayers     `xml:"layer"`
+	ObjectLayers   *ObjectLayers   `xml:"objectgroup"`
+	ImageLayers    *ImageLayers    `xml:"imagelayer"`
+	Groups         *Groups         `xml:"group"`
+
+	// Layers holds every top level layer and Group in document order
+	Layers []Layer `xml:"-"`
+	// Project the Map was loaded against with WithProject, if any
+	Project *Project `xml:"-"`
+
+	warnings []string
+	// Options the Map was loaded with, reused by ReloadTileset
+	options options
+
+	// Unrecognised attributes and elements, kept so they can be written back out
+	UnknownAttrs    []xml.Attr       `xml:",any,attr"`
+	UnknownElements []UnknownElement `xml:",any"`
+}
+
+// Warnings returns the non-fatal problems found while decoding the Map
+func (t *Map) Warnings() []string {
+	return t.warnings
+}
+
+// RequireProperties checks the Map declares a Property of the expected PropertyType for every name in the schema,
+// returning an ErrPropertyMissing or ErrPropertyWrongType error for each one that doesn't, in name order. Returns `nil`
+// if the schema is satisfied.
+func (t *Map) RequireProperties(schema map[string]PropertyType) []error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(schema)) {
+		var p *Property
+		if t.Properties != nil {
+			p = t.Properties.WithName(name)
+		}
+		if p == nil {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrPropertyMissing, name))
+			continue
+		}
+		if p.Type != schema[name] {
+			want, _ := schema[name].MarshalText()
+			got, _ := p.Type.MarshalText()
+			errs = append(errs, fmt.Errorf("%w: %q is %s, want %s", ErrPropertyWrongType, name, got, want))
+		}
+	}
+	return errs
 }
 
+// Validate checks the Map for consistency, returning the errors of Tileset.Validate for each Tileset, and for each
+// templated tile Object an ErrTemplateTilesetMissing error when its template Tileset is not a Tileset of the Map, or an
+// ErrTemplateTilesetMoved error when the Tileset has since moved to another FirstGlobalID, naming the template. The
+// GlobalID of such an Object would resolve to the wrong tile. Returns `nil` if the Map is consistent.
+func (t *Map) Validate() []error {
+	var errs []error
+
+	resolved := map[string]*Tileset{}
+	if t.Tilesets != nil {
+		for _, ts := range *t.Tilesets {
+			errs = append(errs, ts.Validate()...)
+			if ts.Source != "" {
+				resolved[ts.sourcePath()] = ts
+			}
+		}
+	}
+
+	_ = t.WalkLayers(func(layer any, _ []string) error {
+		ol, ok := layer.(*ObjectLayer)
+		if !ok || ol.Objects == nil {
+			return nil
+		}
+		for _, o := range *ol.Objects {
+			if o.templateTileset == "" {
+				continue
+			}
+			switch ts := resolved[o.templateTileset]; {
+			case ts == nil:
+				errs = append(errs, fmt.Errorf("%w: object %d template %s tileset %s", ErrTemplateTilesetMissing,
+					o.ObjectID, o.templatePath, o.templateTileset))
+			case ts.FirstGlobalID != o.templateFirstGID:
+				errs = append(errs, fmt.Errorf("%w: object %d template %s tileset %s firstgid %d, object expects %d",
+					ErrTemplateTilesetMoved, o.ObjectID, o.templatePath, o.templateTileset, ts.FirstGlobalID,
+					o.templateFirstGID))
+			}
+		}
+		return nil
+	})
+	return errs
+}
+
+// EffectiveProperties returns the Map Properties merged over the defaults of the Map class: those the Project declares,
+// or with a `nil` Project those registered with RegisterClass. Returns the Map Properties when the class is unknown or
+// its defaults fail to resolve.
+func (t *Map) EffectiveProperties(proj *Project) *Properties {
+	return inheritClass(t.Properties, t.Class, proj)
+}
+
+// ParallaxOrigin returns the reference point of layer parallax factors, in pixels; 0, 0 when the Map doesn't set one
+func (t *Map) ParallaxOrigin() (x, y float32) {
+	return t.ParallaxOriginX, t.ParallaxOriginY
+}
+
+// NewMap returns an empty orthogonal Map of width by height tiles, each tileWidth by tileHeight pixels
+func NewMap(width, height, tileWidth, tileHeight int) *Map {
+	return &Map{
+		Version:      "1.10",
+		Orientation:  Orthogonal,
+		RenderOrder:  RightDown,
+		Width:        width,
+		Height:       height,
+		TileWidth:    tileWidth,
+		TileHeight:   tileHeight,
+		NextLayerID:  1,
+		NextObjectID: 1,
+		Tilesets:     &Tilesets{},
+	}
+}
+
+// AllocLayerID returns the next layer ID of the Map and advances NextLayerID, so layers added to the Map keep unique IDs
+// when Tiled reopens it. IDs start at 1.
+func (t *Map) AllocLayerID() LayerID {
+	if t.NextLayerID <= 0 {
+		t.NextLayerID = 1
+	}
+	id := t.NextLayerID
+	t.NextLayerID++
+	return id
+}
+
+// AllocObjectID returns the next Object ID of the Map and advances NextObjectID, so Objects added to the Map keep
+// unique IDs when Tiled reopens it. IDs start at 1.
+func (t *Map) AllocObjectID() ObjectID {
+	if t.NextObjectID <= 0 {
+		t.NextObjectID = 1
+	}
+	id := ObjectID(t.NextObjectID)
+	t.NextObjectID++
+	return id
+}
+
+// AddTileLayer appends an empty, visible and opaque TileLayer covering the Map and assigns it the next layer ID
+func (t *Map) AddTileLayer(name string) *TileLayer {
+	l := &TileLayer{
+		ID:       t.AllocLayerID(),
+		Name:     name,
+		Width:    t.Width,
+		Height:   t.Height,
+		Opacity:  1,
+		Visible:  true,
+		RawData:  &Data{Encoding: "csv"},
+		TileDefs: make([]*TileDef, t.Width*t.Height),
+	}
+	for i := range l.TileDefs {
+		l.TileDefs[i] = &TileDef{Nil: true}
+	}
+
+	if t.TileLayers == nil {
+		t.TileLayers = &TileLayers{}
+	}
+	*t.TileLayers = append(*t.TileLayers, l)
+	t.Layers = append(t.Layers, l)
+
+	return l
+}
+
+// PixelSize returns the size in pixels of the rectangle bounding the Map, according to its Orientation
+func (t *Map) PixelSize() (w, h int) {
+	switch t.Orientation {
+	case Isometric:
+		return (t.Width + t.Height) * t.TileWidth / 2, (t.Width + t.Height) * t.TileHeight / 2
+	case Staggered, Hexagonal:
+		tw, th := t.TileWidth&^1, t.TileHeight&^1
+
+		var sideX, sideY int
+		if t.Orientation == Hexagonal {
+			if t.StaggerAxis == "x" {
+				sideX = t.HexSideLength
+			} else {
+				sideY = t.HexSideLength
+			}
+		}
+		offsetX, offsetY := (tw-sideX)/2, (th-sideY)/2
+		columnWidth, rowHeight := offsetX+sideX, offsetY+sideY
+
+		if t.StaggerAxis == "x" {
+			w, h = t.Width*columnWidth+offsetX, t.Height*(th+sideY)
+			if t.Width > 1 {
+				h += rowHeight
+			}
+			return
+		}
+
+		w, h = t.Width*(tw+sideX), t.Height*rowHeight+offsetY
+		if t.Height > 1 {
+			w += columnWidth
+		}
+		return
+	default:
+		return t.Width * t.TileWidth, t.Height * t.TileHeight
+	}
+}
+
+// EditorSettings holds the editor specific settings of a Map, such as the chunk size of infinite maps
+type EditorSettings struct {
+	ChunkSize ChunkSize `xml:"chunksize" json:"chunksize"`
+	Export    *Export   `xml:"export" json:"export,omitempty"`
+}
+
+// ChunkSize is the size in tiles of the chunks of infinite maps, 16x16 unless specified
+type ChunkSize struct {
+	Width  int `xml:"width,attr" json:"width"`
+	Height int `xml:"height,attr" json:"height"`
+}
+
+// Export is the last export target and format of a Map
+type Export struct {
+	Target string `xml:"target,attr" json:"target"`
+	Format string `xml:"format,attr" json:"format"`
+}
+
+// Default chunk size of infinite maps
+const (
+	DefaultChunkWidth  = 16
+	DefaultChunkHeight = 16
+)
+
 type Orientation int
 
 const (
@@ -52,17 +277,60 @@ const (
 	LeftUp
 )
 
+// UnmarshalXML decodes a Map on its own, as xml.Unmarshal does, with the zero Options; see lockDecode
 func (t *Map) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
+	defer lockDecode()()
+	return t.decodeXML(xd, start)
+}
+
+// decodingMap decodes a Map for a caller already holding decodeMu, such as New, keeping the Options it applied
+type decodingMap Map
+
+func (d *decodingMap) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
+	return (*Map)(d).decodeXML(xd, start)
+}
+
+// decodeXML decodes a TMX map element; callers must hold decodeMu
+func (t *Map) decodeXML(xd *xml.Decoder, start xml.StartElement) error {
 	type tmpTilemap Map
 	var tmp tmpTilemap
 
+	decodeWarnings = nil
+	defer func() {
+		t.warnings, decodeWarnings = decodeWarnings, nil
+	}()
+
 	if err := xd.DecodeElement(&tmp, &start); err != nil {
 		return fmt.Errorf("%w: %w", ErrDecodingTilemap, err)
 	}
 
 	*t = (Map)(tmp)
+	t.Layers = documentOrder(t.TileLayers, t.ObjectLayers, t.ImageLayers, t.Groups)
+
+	return t.finishDecode()
+}
+
+// finishDecode completes a Map decoded from either format once its layers are in place: it fills in the default editor
+// settings, orders the Tilesets, rebases template GlobalIDs and, unless decoding with HeaderOnly, resolves the TileDefs
+func (t *Map) finishDecode() error {
+	if t.EditorSettings == nil {
+		t.EditorSettings = &EditorSettings{}
+	}
+	if t.EditorSettings.ChunkSize.Width == 0 {
+		t.EditorSettings.ChunkSize.Width = DefaultChunkWidth
+	}
+	if t.EditorSettings.ChunkSize.Height == 0 {
+		t.EditorSettings.ChunkSize.Height = DefaultChunkHeight
+	}
+
+	if t.Tilesets != nil {
+		t.Tilesets.SortByFirstGID()
+	}
+	t.rebaseTemplateGlobalIDs()
 
-	sort.Sort(byFirstGlobalID(*t.Tilesets))
+	if decodeOptions.headerOnly {
+		return nil
+	}
 
 	if t.TileLayers != nil {
 		for _, tl := range *t.TileLayers {
@@ -79,6 +347,320 @@ func (t *Map) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 	return nil
 }
 
+// rebaseTemplateGlobalIDs moves the GlobalIDs that Objects inherited from a template, counted from the FirstGlobalID of
+// the template Tileset, onto the FirstGlobalID the Map gives the same Tileset, keeping the flip flags. Objects whose
+// template Tileset is not a Tileset of the Map are left for Validate to report.
+func (t *Map) rebaseTemplateGlobalIDs() {
+	if t.Tilesets == nil {
+		return
+	}
+	bySource := map[string]*Tileset{}
+	for _, ts := range *t.Tilesets {
+		if ts.Source != "" {
+			bySource[ts.sourcePath()] = ts
+		}
+	}
+
+	_ = t.WalkLayers(func(layer any, _ []string) error {
+		ol, ok := layer.(*ObjectLayer)
+		if !ok || ol.Objects == nil {
+			return nil
+		}
+		for _, o := range *ol.Objects {
+			ts := bySource[o.templateTileset]
+			if o.templateTileset == "" || ts == nil || ts.FirstGlobalID == o.templateFirstGID {
+				continue
+			}
+			bare := GlobalID(o.GlobalID.BareID()) - o.templateFirstGID + ts.FirstGlobalID
+			o.GlobalID = bare | o.GlobalID&TileFlipped
+			o.templateFirstGID = ts.FirstGlobalID
+		}
+		return nil
+	})
+}
+
+// LayerByID retrieves the TileLayer, ObjectLayer, ImageLayer or Group with the given LayerID, including those nested in
+// Groups. Returns false if not found.
+func (t *Map) LayerByID(id LayerID) (any, bool) {
+	var found any
+	_ = t.WalkLayers(func(layer any, _ []string) error {
+		var lid LayerID
+		switch l := layer.(type) {
+		case *TileLayer:
+			lid = l.ID
+		case *ObjectLayer:
+			lid = l.ID
+		case *ImageLayer:
+			lid = l.ID
+		case *Group:
+			lid = l.ID
+		}
+		if lid == id {
+			found = layer
+			return errStopWalk
+		}
+		return nil
+	})
+	return found, found != nil
+}
+
+// EffectiveTint returns the tint to draw the layer at layerPath with, multiplying the TintColor of the layer with
+// those of its enclosing Groups. layerPath holds the names of the enclosing Groups followed by the name of the layer.
+// Layers without a TintColor, invalid tints and paths that don't resolve leave the channels untouched, so the result
+// is opaque white when nothing is tinted. The channels are multiplied directly and are not alpha-premultiplied.
+func (t *Map) EffectiveTint(layerPath []string) color.RGBA {
+	tint := white
+	layers := t.Layers
+	for _, name := range layerPath {
+		var next Layer
+		for _, l := range layers {
+			if l.LayerName() == name {
+				next = l
+				break
+			}
+		}
+		if next == nil {
+			return white
+		}
+
+		if c, err := ParseColor(tintColor(next)); err == nil {
+			tint = multiplyColor(tint, c)
+		}
+
+		layers = nil
+		if g, ok := next.(*Group); ok {
+			layers = g.Layers
+		}
+	}
+	return tint
+}
+
+// EffectiveOpacity returns the opacity to draw the layer with, multiplying its Opacity with those of its enclosing
+// Groups. Returns the layer's own Opacity if it isn't part of the Map.
+func (t *Map) EffectiveOpacity(layer Layer) float32 {
+	opacity := layer.LayerOpacity()
+	for _, g := range ancestors(t.Layers, layer) {
+		opacity *= g.Opacity
+	}
+	return opacity
+}
+
+// ancestors returns the Groups enclosing target, outermost first; nil if target is top level or not found
+func ancestors(layers []Layer, target Layer) []*Group {
+	for _, l := range layers {
+		g, ok := l.(*Group)
+		if !ok {
+			continue
+		}
+		if slices.Contains(g.Layers, target) {
+			return []*Group{g}
+		}
+		if gs := ancestors(g.Layers, target); gs != nil {
+			return append([]*Group{g}, gs...)
+		}
+	}
+	return nil
+}
+
+func tintColor(l Layer) string {
+	switch l := l.(type) {
+	case *TileLayer:
+		return l.TintColor
+	case *ObjectLayer:
+		return l.TintColor
+	case *ImageLayer:
+		return l.TintColor
+	case *Group:
+		return l.TintColor
+	}
+	return ""
+}
+
+// ObjectByID retrieves the Object with a given ObjectID from every ObjectLayer in the Map, including those nested in
+// Groups. Returns `nil` if not found.
+func (t *Map) ObjectByID(id ObjectID) *Object {
+	var object *Object
+	_ = t.WalkLayers(func(layer any, _ []string) error {
+		if ol, ok := layer.(*ObjectLayer); ok && ol.Objects != nil {
+			if object = ol.Objects.WithID(id); object != nil {
+				return errStopWalk
+			}
+		}
+		return nil
+	})
+	return object
+}
+
+// ObjectsWithClass retrieves all Objects with a given class from every ObjectLayer in the Map, including those nested
+// in Groups. Returns `nil` if none found.
+func (t *Map) ObjectsWithClass(class string) Objects {
+	var objects Objects
+	_ = t.WalkLayers(func(layer any, _ []string) error {
+		if ol, ok := layer.(*ObjectLayer); ok && ol.Objects != nil {
+			objects = append(objects, ol.Objects.WithClass(class)...)
+		}
+		return nil
+	})
+	return objects
+}
+
+// LocateTileDef finds the TileLayer, including those nested in Groups, holding the given TileDef pointer and the row and
+// column of the TileDef within it. TileDefs are allocated one by one, so every layer is scanned; ok is false if no
+// layer holds the pointer.
+func (t *Map) LocateTileDef(td *TileDef) (layer *TileLayer, row, col int, ok bool) {
+	if td == nil {
+		return nil, 0, 0, false
+	}
+
+	_ = t.WalkLayers(func(l any, _ []string) error {
+		tl, isTileLayer := l.(*TileLayer)
+		if !isTileLayer {
+			return nil
+		}
+		for i, d := range tl.TileDefs {
+			if d != td {
+				continue
+			}
+			if r, c, err := tl.PositionOf(i); err == nil {
+				layer, row, col, ok = tl, r, c, true
+				return errStopWalk
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// TileObjects retrieves every tile Object, those with a GlobalID, across all ObjectLayers of the Map including those
+// nested in Groups, in document order. Returns `nil` if none found.
+func (t *Map) TileObjects() []*Object {
+	var objects []*Object
+	_ = t.WalkLayers(func(layer any, _ []string) error {
+		if ol, ok := layer.(*ObjectLayer); ok && ol.Objects != nil {
+			for _, o := range *ol.Objects {
+				if o.GlobalID != 0 {
+					objects = append(objects, o)
+				}
+			}
+		}
+		return nil
+	})
+	return objects
+}
+
+// TileObjectDef is a tile Object with the TileDef its GlobalID resolves to; see (*Map).TileObjectDefs
+type TileObjectDef struct {
+	Object  *Object
+	TileDef *TileDef
+}
+
+// TileObjectDefs retrieves every tile Object as TileObjects does, each with the TileDef its GlobalID resolves to against
+// the Tilesets of the Map. Returns an error if a GlobalID matches no Tileset.
+func (t *Map) TileObjectDefs() ([]TileObjectDef, error) {
+	var defs []TileObjectDef
+	for _, o := range t.TileObjects() {
+		td, err := newTileDef(o.GlobalID, t.Tilesets)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, TileObjectDef{Object: o, TileDef: td})
+	}
+	return defs, nil
+}
+
+// errStopWalk is returned by internal WalkLayers callbacks to end a walk early
+var errStopWalk = errors.New("stop walk")
+
+// WalkLayers visits every TileLayer, ObjectLayer, ImageLayer and Group of the Map depth-first in document (draw) order.
+// Groups are visited before their children; path holds the names of the enclosing Groups. Walking stops at the first
+// error returned by fn.
+func (t *Map) WalkLayers(fn func(layer any, path []string) error) error {
+	return walkLayers(t.Layers, nil, fn)
+}
+
+// WalkProperties calls fn with every set of Properties in the Map and its owner: the Map, each Tileset and its
+// Terrains, Tiles, WangSets and WangColors, then each layer, Group and Object in WalkLayers order. The members of a class
+// Property are visited right after the Properties holding it, with the class Property as their owner. Owners without
+// Properties are skipped.
+func (t *Map) WalkProperties(fn func(owner any, props *Properties)) {
+	walkProperties(t, t.Properties, fn)
+
+	if t.Tilesets != nil {
+		for _, ts := range *t.Tilesets {
+			walkProperties(ts, ts.Properties, fn)
+			if ts.TerrainTypes != nil {
+				for _, terrain := range *ts.TerrainTypes {
+					walkProperties(terrain, terrain.Properties, fn)
+				}
+			}
+			if ts.HasTiles() {
+				for _, tile := range *ts.Tiles {
+					walkProperties(tile, tile.Properties, fn)
+				}
+			}
+			if ts.WangSets != nil {
+				for _, ws := range *ts.WangSets {
+					walkProperties(ws, ws.Properties, fn)
+					if ws.WangColors != nil {
+						for _, wc := range *ws.WangColors {
+							walkProperties(wc, wc.Properties, fn)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	_ = t.WalkLayers(func(layer any, _ []string) error {
+		switch l := layer.(type) {
+		case *TileLayer:
+			walkProperties(l, l.Properties, fn)
+		case *ImageLayer:
+			walkProperties(l, l.Properties, fn)
+		case *Group:
+			walkProperties(l, l.Properties, fn)
+		case *ObjectLayer:
+			walkProperties(l, l.Properties, fn)
+			if l.Objects != nil {
+				for _, o := range *l.Objects {
+					walkProperties(o, o.Properties, fn)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func walkProperties(owner any, props *Properties, fn func(owner any, props *Properties)) {
+	if props == nil {
+		return
+	}
+
+	fn(owner, props)
+	for _, p := range *props {
+		walkProperties(p, p.Properties, fn)
+	}
+}
+
+func walkLayers(layers []Layer, path []string, fn func(layer any, path []string) error) error {
+	for _, l := range layers {
+		if err := fn(l, path); err != nil {
+			return err
+		}
+
+		g, ok := l.(*Group)
+		if !ok {
+			continue
+		}
+
+		if err := walkLayers(g.Layers, append(path[:len(path):len(path)], g.Name), fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func decodeGroupTileDefs(gl *Groups, tss *Tilesets) error {
 	if gl == nil {
 		return nil
@@ -104,53 +686,261 @@ func decodeGroupTileDefs(gl *Groups, tss *Tilesets) error {
 // TileDefs gets the definitions for all the tiles in a given TileLayer, matched with the given Tilesets
 func decodeTileDefs(l *TileLayer, tss *Tilesets) (err error) {
 	for _, tgr := range l.TileGlobalRefs {
-		bid := tgr.GlobalID.BareID()
+		td, err := newTileDef(tgr.GlobalID, tss)
+		if err != nil {
+			return err
+		}
+		l.TileDefs = append(l.TileDefs, td)
+	}
+	// Release memory
+	l.TileGlobalRefs = nil
+	return nil
+}
 
-		if bid == 0 {
-			l.TileDefs = append(l.TileDefs, &TileDef{Nil: true})
-			continue
+// ReloadTileset decodes the external Tileset with the given source again, from the file it was loaded from and with the
+// Options the Map was loaded with, keeping its FirstGlobalID. The reloaded Tileset replaces the old one in Tilesets,
+// and the TileDefs of every TileLayer drawn from the old Tileset are resolved against it. Returns ErrTilesetNotFound
+// if the Map has no external Tileset with that source; the Map is left unchanged on error.
+func (t *Map) ReloadTileset(source string) error {
+	if t.Tilesets == nil {
+		return fmt.Errorf("%w: %s", ErrTilesetNotFound, source)
+	}
+	i := slices.IndexFunc(*t.Tilesets, func(ts *Tileset) bool {
+		return ts.Source != "" && (ts.Source == source || ts.sourcePath() == filepath.Clean(source))
+	})
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrTilesetNotFound, source)
+	}
+	old := (*t.Tilesets)[i]
+
+	decodeMu.Lock()
+	defer decodeMu.Unlock()
+
+	decodeOptions = t.options
+	defer func() {
+		t.warnings = append(t.warnings, decodeWarnings...)
+		resetDecode()
+	}()
+
+	ts := &Tileset{FirstGlobalID: old.FirstGlobalID, Source: old.Source}
+	path := old.path
+	if path == "" {
+		path = old.Source
+	}
+	if err := ts.loadExternal(path, old.FirstGlobalID); err != nil {
+		return err
+	}
+
+	tss := slices.Clone(*t.Tilesets)
+	tss[i] = ts
+	var redefs []*TileDef
+	var resolved []TileDef
+	err := t.WalkLayers(func(layer any, _ []string) error {
+		l, ok := layer.(*TileLayer)
+		if !ok {
+			return nil
+		}
+		for _, td := range l.TileDefs {
+			if td.Nil || td.TileSet != old {
+				continue
+			}
+			nd, err := tileDefOf(td.GlobalID, &tss)
+			if err != nil {
+				return err
+			}
+			redefs, resolved = append(redefs, td), append(resolved, nd)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-		var ts *Tileset
-		for _, i := range *tss {
-			t := i
-			if bid < uint32(t.FirstGlobalID) {
-				break
+	*t.Tilesets = tss
+	for j, td := range redefs {
+		*td = resolved[j]
+	}
+	return nil
+}
+
+// UsedTilesets retrieves the Tilesets referenced by at least one tile of a TileLayer or tile Object, in Tilesets order.
+// Returns `nil` if none are used.
+func (t *Map) UsedTilesets() Tilesets {
+	if t.Tilesets == nil {
+		return nil
+	}
+
+	used := map[*Tileset]bool{}
+	_ = t.WalkLayers(func(layer any, _ []string) error {
+		switch l := layer.(type) {
+		case *TileLayer:
+			for _, td := range l.TileDefs {
+				if !td.Nil && td.TileSet != nil {
+					used[td.TileSet] = true
+				}
 			}
+		case *ObjectLayer:
+			if l.Objects == nil {
+				return nil
+			}
+			for _, o := range *l.Objects {
+				if ts := t.TilesetForGID(o.GlobalID); ts != nil {
+					used[ts] = true
+				}
+			}
+		}
+		return nil
+	})
 
-			ts = t
+	var tilesets Tilesets
+	for _, ts := range *t.Tilesets {
+		if used[ts] {
+			tilesets = append(tilesets, ts)
 		}
+	}
+	return tilesets
+}
 
-		// if we never found a Tileset, the file is invalid; return an error that
-		if ts == nil {
-			return fmt.Errorf("%w, with global ID %v", ErrNoSuitableTileset, tgr.GlobalID)
+// TileHistogram counts how many times each GlobalID appears across every TileLayer of the Map, including those nested
+// in Groups. Flipped tiles are counted under their flipped GlobalID; empty tiles are not counted.
+func (t *Map) TileHistogram() map[GlobalID]int {
+	histogram := map[GlobalID]int{}
+	_ = t.WalkLayers(func(layer any, _ []string) error {
+		if tl, ok := layer.(*TileLayer); ok {
+			for gid, n := range tl.TileHistogram() {
+				histogram[gid] += n
+			}
 		}
+		return nil
+	})
+	return histogram
+}
 
-		var tile *Tile = nil
-		id := tgr.GlobalID.TileID(ts)
-		if ts.HasTiles() {
-			tile = ts.Tiles.WithID(id)
+// RemapGlobalIDs shifts every tile reference of the Map, in TileLayers and tile Objects, and the FirstGlobalID of every
+// Tileset by offset, keeping tile flip flags. TileDefs keep their Tileset and TileID, so they still resolve against the
+// shifted Tilesets; this is the basis for merging the Tilesets of several Maps. Returns an ErrGlobalIDOverflow error,
+// leaving the Map unchanged, if a shifted GlobalID or Tileset tile would run into the flip flags.
+func (t *Map) RemapGlobalIDs(offset uint32) error {
+	var highest uint64
+	t.eachGlobalIDRef(func(gid *GlobalID) {
+		highest = max(highest, uint64(gid.BareID()))
+	})
+	if t.Tilesets != nil {
+		for _, ts := range *t.Tilesets {
+			if n := ts.EffectiveTileCount(); n > 0 {
+				highest = max(highest, uint64(ts.FirstGlobalID.BareID())+uint64(n)-1)
+			}
 		}
-		l.TileDefs = append(l.TileDefs, &TileDef{
-			ID:                  id,
-			GlobalID:            tgr.GlobalID,
-			TileSet:             ts,
-			Tile:                tile,
-			HorizontallyFlipped: tgr.GlobalID.IsFlippedHorizontally(),
-			VerticallyFlipped:   tgr.GlobalID.IsFlippedVertically(),
-			DiagonallyFlipped:   tgr.GlobalID.IsFlippedDiagonally(),
-		})
 	}
-	// Release memory
-	l.TileGlobalRefs = nil
+	if highest+uint64(offset) > uint64(^GlobalID(TileFlipped)) {
+		return fmt.Errorf("%w: %d + %d", ErrGlobalIDOverflow, highest, offset)
+	}
+
+	t.eachGlobalIDRef(func(gid *GlobalID) {
+		if gid.BareID() != 0 {
+			*gid = GlobalID(gid.BareID()+offset) | *gid&TileFlipped
+		}
+	})
 	return nil
 }
 
+// eachGlobalIDRef calls fn with every GlobalID of the Map: the FirstGlobalID of each Tileset, the tiles of each
+// TileLayer and the GlobalID of each Object, with the template FirstGlobalID it counts from
+func (t *Map) eachGlobalIDRef(fn func(gid *GlobalID)) {
+	if t.Tilesets != nil {
+		for _, ts := range *t.Tilesets {
+			fn(&ts.FirstGlobalID)
+		}
+	}
+
+	_ = t.WalkLayers(func(layer any, _ []string) error {
+		switch l := layer.(type) {
+		case *TileLayer:
+			for _, tgr := range l.TileGlobalRefs {
+				fn(&tgr.GlobalID)
+			}
+			for _, td := range l.TileDefs {
+				fn(&td.GlobalID)
+			}
+		case *ObjectLayer:
+			if l.Objects == nil {
+				return nil
+			}
+			for _, o := range *l.Objects {
+				fn(&o.GlobalID)
+				fn(&o.templateFirstGID)
+			}
+		}
+		return nil
+	})
+}
+
+// TilesetForGID retrieves the Tileset containing the tile referenced by the given GlobalID. Returns `nil` if not found.
+func (t *Map) TilesetForGID(gid GlobalID) *Tileset {
+	return tilesetForGID(gid, t.Tilesets)
+}
+
+func tilesetForGID(gid GlobalID, tss *Tilesets) *Tileset {
+	bid := gid.BareID()
+	if bid == 0 || tss == nil {
+		return nil
+	}
+
+	var ts *Tileset
+	for _, i := range *tss {
+		t := i
+		if bid < uint32(t.FirstGlobalID) {
+			break
+		}
+
+		ts = t
+	}
+	return ts
+}
+
+// newTileDef hydrates a TileDef for the given GlobalID from the Tilesets sorted by FirstGlobalID
+func newTileDef(gid GlobalID, tss *Tilesets) (*TileDef, error) {
+	td, err := tileDefOf(gid, tss)
+	if err != nil {
+		return nil, err
+	}
+	return &td, nil
+}
+
+// tileDefOf is newTileDef without the allocation
+func tileDefOf(gid GlobalID, tss *Tilesets) (TileDef, error) {
+	if gid.BareID() == 0 {
+		return TileDef{Nil: true}, nil
+	}
+
+	ts := tilesetForGID(gid, tss)
+	// if we never found a Tileset, the file is invalid; return an error that
+	if ts == nil {
+		return TileDef{}, fmt.Errorf("%w, with global ID %v", ErrNoSuitableTileset, gid)
+	}
+
+	var tile *Tile = nil
+	id := gid.TileID(ts)
+	if ts.HasTiles() {
+		tile = ts.Tiles.WithID(id)
+	}
+	return TileDef{
+		ID:                  id,
+		GlobalID:            gid,
+		TileSet:             ts,
+		Tile:                tile,
+		HorizontallyFlipped: gid.IsFlippedHorizontally(),
+		VerticallyFlipped:   gid.IsFlippedVertically(),
+		DiagonallyFlipped:   gid.IsFlippedDiagonally(),
+	}, nil
+}
+
 func (o *Orientation) UnmarshalText(text []byte) error {
 	s := strings.ToLower(string(text))
 	switch strings.ToLower(s) {
 	default:
-		return fmt.Errorf("%w: %s", ErrUnknownOrientation, s)
+		*o = Orthogonal
+		return unknownEnum(ErrUnknownOrientation, s)
 	case "orthogonal":
 		*o = Orthogonal
 	case "isometric":
@@ -163,11 +953,26 @@ func (o *Orientation) UnmarshalText(text []byte) error {
 	return nil
 }
 
+func (o Orientation) MarshalText() ([]byte, error) {
+	switch o {
+	case Orthogonal:
+		return []byte("orthogonal"), nil
+	case Isometric:
+		return []byte("isometric"), nil
+	case Staggered:
+		return []byte("staggered"), nil
+	case Hexagonal:
+		return []byte("hexagonal"), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownOrientation, o)
+}
+
 func (r *RenderOrder) UnmarshalText(text []byte) error {
 	s := strings.ToLower(string(text))
 	switch strings.ToLower(s) {
 	default:
-		return fmt.Errorf("%w: %s", ErrUnknownRenderOrder, s)
+		*r = RightDown
+		return unknownEnum(ErrUnknownRenderOrder, s)
 	case "right-down":
 		*r = RightDown
 	case "right-up":
@@ -179,3 +984,17 @@ func (r *RenderOrder) UnmarshalText(text []byte) error {
 	}
 	return nil
 }
+
+func (r RenderOrder) MarshalText() ([]byte, error) {
+	switch r {
+	case RightDown:
+		return []byte("right-down"), nil
+	case RightUp:
+		return []byte("right-up"), nil
+	case LeftDown:
+		return []byte("left-down"), nil
+	case LeftUp:
+		return []byte("left-up"), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownRenderOrder, r)
+}
@@ -0,0 +1,108 @@
+package tiled
+
+import (
+	"math"
+	"sort"
+)
+
+// objectIndex is a uniform grid over Object bounds, mapping each cell to the indices of the Objects overlapping it
+type objectIndex struct {
+	cellSize float32
+	cells    map[[2]int][]int
+}
+
+// BuildIndex builds a uniform grid spatial index with square cells of cellSize pixels over the ObjectLayer, used by
+// QueryRect and QueryPoint. The index must be rebuilt after Objects are added, removed or moved.
+func (t *ObjectLayer) BuildIndex(cellSize int) {
+	if cellSize <= 0 || t.Objects == nil {
+		t.index = nil
+		return
+	}
+
+	idx := &objectIndex{cellSize: float32(cellSize), cells: map[[2]int][]int{}}
+	for i, o := range *t.Objects {
+		minX, minY, maxX, maxY := o.bounds()
+		x1, y1 := idx.cell(minX, minY)
+		x2, y2 := idx.cell(maxX, maxY)
+		for cx := x1; cx <= x2; cx++ {
+			for cy := y1; cy <= y2; cy++ {
+				idx.cells[[2]int{cx, cy}] = append(idx.cells[[2]int{cx, cy}], i)
+			}
+		}
+	}
+	t.index = idx
+}
+
+// QueryRect retrieves all Objects whose bounds intersect the given Rect, nil if none. Uses the index built by
+// BuildIndex, falling back to ObjectsInRect when there is none.
+func (t *ObjectLayer) QueryRect(r Rect) Objects {
+	if t.index == nil {
+		return t.ObjectsInRect(r)
+	}
+
+	x1, y1 := t.index.cell(float32(r.Min.X), float32(r.Min.Y))
+	x2, y2 := t.index.cell(float32(r.Max.X), float32(r.Max.Y))
+	return t.query(x1, y1, x2, y2, func(o *Object) bool {
+		return o.intersects(r)
+	})
+}
+
+// QueryPoint retrieves all Objects whose bounds contain the given point, nil if none. Uses the index built by
+// BuildIndex, falling back to a linear scan when there is none.
+func (t *ObjectLayer) QueryPoint(x, y float32) Objects {
+	contains := func(o *Object) bool {
+		minX, minY, maxX, maxY := o.bounds()
+		return x >= minX && x <= maxX && y >= minY && y <= maxY
+	}
+
+	if t.index == nil {
+		if t.Objects == nil {
+			return nil
+		}
+
+		var objects Objects
+		for _, o := range *t.Objects {
+			if contains(o) {
+				objects = append(objects, o)
+			}
+		}
+		return objects
+	}
+
+	cx, cy := t.index.cell(x, y)
+	return t.query(cx, cy, cx, cy, contains)
+}
+
+// query collects the Objects in the given cell range that match, in their ObjectLayer order
+func (t *ObjectLayer) query(x1, y1, x2, y2 int, match func(o *Object) bool) Objects {
+	seen := map[int]bool{}
+	var hits []int
+	for cx := x1; cx <= x2; cx++ {
+		for cy := y1; cy <= y2; cy++ {
+			for _, i := range t.index.cells[[2]int{cx, cy}] {
+				if seen[i] {
+					continue
+				}
+				seen[i] = true
+				if match((*t.Objects)[i]) {
+					hits = append(hits, i)
+				}
+			}
+		}
+	}
+
+	if len(hits) == 0 {
+		return nil
+	}
+
+	sort.Ints(hits)
+	objects := make(Objects, len(hits))
+	for n, i := range hits {
+		objects[n] = (*t.Objects)[i]
+	}
+	return objects
+}
+
+func (idx *objectIndex) cell(x, y float32) (int, int) {
+	return int(math.Floor(float64(x / idx.cellSize))), int(math.Floor(float64(y / idx.cellSize)))
+}
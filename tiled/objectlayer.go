@@ -2,10 +2,12 @@ package tiled
 
 import (
 	"encoding/xml"
-	"errors"
 	"fmt"
-	"os"
+	"image/color"
+	"math"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -25,7 +27,7 @@ func (ol ObjectLayers) WithName(name string) *ObjectLayer {
 
 // ObjectLayer aka <objectgroup> is a Group of Objects within a Map or tile, used to specify sub-Objects such as polygons.
 type ObjectLayer struct {
-	ID        string    `xml:"nid,attr"`
+	ID        LayerID   `xml:"id,attr"`
 	Name      string    `xml:"name,attr"`
 	Class     string    `xml:"class,attr"`
 	Color     string    `xml:"color,attr"`
@@ -35,14 +37,61 @@ type ObjectLayer struct {
 	Height    int       `xml:"height,attr"`
 	Opacity   float32   `xml:"opacity,attr"`
 	Visible   bool      `xml:"visible,attr"`
+	Locked    bool      `xml:"locked,attr"`
 	OffsetX   int       `xml:"offsetx,attr"`
 	OffsetY   int       `xml:"offsety,attr"`
 	ParallaxX float32   `xml:"parallaxx,attr"`
 	ParallaxY float32   `xml:"parallaxy,attr"`
 	DrawOrder DrawOrder `xml:"draworder,attr"`
+	TintColor string    `xml:"tintcolor,attr"`
 
 	Properties *Properties `xml:"properties>property"`
 	Objects    *Objects    `xml:"object"`
+
+	// Spatial index built by BuildIndex
+	index *objectIndex
+	// Parent is the Group holding the layer; `nil` at the top level of the Map
+	Parent *Group `xml:"-"`
+
+	// Byte offset of the end of the element within the map document, used to recover draw order
+	offset int64
+
+	// Unrecognised attributes and elements, kept so they can be written back out
+	UnknownAttrs    []xml.Attr       `xml:",any,attr"`
+	UnknownElements []UnknownElement `xml:",any"`
+}
+
+// ObjectsInRect retrieves all Objects whose bounds intersect the given Rect, nil if none. Object bounds account for
+// rotation, polygon and polyline points, and the bottom-left origin of tile Objects.
+func (t *ObjectLayer) ObjectsInRect(r Rect) Objects {
+	if t.Objects == nil {
+		return nil
+	}
+
+	var objects Objects
+	for _, o := range *t.Objects {
+		if o.intersects(r) {
+			objects = append(objects, o)
+		}
+	}
+	return objects
+}
+
+// OrderedObjects returns the Objects in the order they should be drawn: sorted by Y for TopDown, in document order for
+// Index. Objects sharing a Y keep their document order. Returns nil if the layer has no Objects.
+func (t *ObjectLayer) OrderedObjects() Objects {
+	if t.Objects == nil {
+		return nil
+	}
+
+	objects := make(Objects, len(*t.Objects))
+	copy(objects, *t.Objects)
+	if t.DrawOrder == TopDown {
+		sort.SliceStable(objects, func(i, j int) bool {
+			return objects[i].Y < objects[j].Y
+		})
+	}
+	return objects
 }
 
 // Objects is an array of Object Objects
@@ -59,6 +108,42 @@ func (ol Objects) WithName(name string) *Object {
 	return nil
 }
 
+// WithID retrieves the Object with a given ObjectID, nil if none
+func (ol Objects) WithID(id ObjectID) *Object {
+	for _, o := range ol {
+		if o.ObjectID == id {
+			return o
+		}
+	}
+
+	return nil
+}
+
+// WithClass retrieves all Objects with a given class, nil if none
+func (ol Objects) WithClass(class string) Objects {
+	var objects Objects
+	for _, o := range ol {
+		if o.Class == class {
+			objects = append(objects, o)
+		}
+	}
+
+	return objects
+}
+
+// WithTypeOrClass retrieves all Objects whose Type or Class matches name, covering maps written before and after Tiled
+// renamed the Object `type` attribute to `class`. Returns `nil` if none found.
+func (ol Objects) WithTypeOrClass(name string) Objects {
+	var objects Objects
+	for _, o := range ol {
+		if o.Type == name || o.Class == name {
+			objects = append(objects, o)
+		}
+	}
+
+	return objects
+}
+
 // ObjectID specifies a unique ID
 type ObjectID uint32
 
@@ -67,6 +152,7 @@ type Object struct {
 	ObjectID ObjectID `xml:"id,attr"`
 	Name     string   `xml:"name,attr"`
 	Type     string   `xml:"type,attr"`
+	Class    string   `xml:"class,attr"`
 	X        float32  `xml:"x,attr"`
 	Y        float32  `xml:"y,attr"`
 	Width    float32  `xml:"width,attr"`
@@ -83,6 +169,108 @@ type Object struct {
 	Text       *Text       `xml:"text"`
 	Point      *struct{}   `xml:"point"`
 	Ellipse    *struct{}   `xml:"ellipse"`
+
+	// Path of the template and its Tileset the Object GlobalID was inherited from, checked by (*Map).Validate
+	templatePath, templateTileset string
+	// FirstGlobalID the inherited GlobalID counts from; the template's until the Map rebases it onto its own Tileset
+	templateFirstGID GlobalID
+	// Project the Object was loaded against with WithProject, whose classes EffectiveProperties resolves
+	project *Project
+
+	// Unrecognised attributes and elements, kept so they can be written back out
+	UnknownAttrs    []xml.Attr       `xml:",any,attr"`
+	UnknownElements []UnknownElement `xml:",any"`
+}
+
+// EffectiveProperties returns the Object Properties merged over the defaults of the Object class, falling back to its
+// type: those the Project the Object was loaded against declares, or without one those registered with RegisterClass.
+// Returns the Object Properties when the class is unknown.
+func (o *Object) EffectiveProperties() *Properties {
+	class := o.Class
+	if class == "" {
+		class = o.Type
+	}
+
+	return inheritClass(o.Properties, class, o.project)
+}
+
+// TileProperties returns the Properties of a tile Object: the Properties of its Tile in the Tileset, overlaid with the
+// Object's own. Returns the Object Properties for Objects that aren't tiles, or whose Tile has no Properties.
+func (o *Object) TileProperties(m *Map) (*Properties, error) {
+	if o.GlobalID.BareID() == 0 {
+		return o.Properties, nil
+	}
+
+	td, err := newTileDef(o.GlobalID, m.Tilesets)
+	if err != nil {
+		return nil, err
+	}
+	if td.Tile == nil || td.Tile.Properties == nil {
+		return o.Properties, nil
+	}
+
+	var own Properties
+	if o.Properties != nil {
+		own = *o.Properties
+	}
+	merged := own.Merge(*td.Tile.Properties)
+	return &merged, nil
+}
+
+// CollisionWorldShapes returns copies of the collision Objects of the Tile drawn by a tile Object, placed in map
+// coordinates: scaled from the tile size to the Object size, then rotated with the Object about its bottom-left origin.
+// The Rotation of each shape adds the Object rotation. Flip flags of the GlobalID are not applied, and shapes that are
+// themselves rotated are scaled along their own axes. Returns `nil` if the Object is not a tile or its Tile has no
+// collision Objects.
+func (o *Object) CollisionWorldShapes(m *Map) ([]*Object, error) {
+	if o.GlobalID.BareID() == 0 {
+		return nil, nil
+	}
+
+	td, err := newTileDef(o.GlobalID, m.Tilesets)
+	if err != nil {
+		return nil, err
+	}
+	if td.Tile == nil || !td.Tile.HasObjectLayer() || td.Tile.ObjectLayer.Objects == nil {
+		return nil, nil
+	}
+
+	src, err := td.SourceRect()
+	if err != nil {
+		return nil, err
+	}
+	tw, th := float64(src.Max.X-src.Min.X), float64(src.Max.Y-src.Min.Y)
+	sx, sy := 1.0, 1.0
+	if o.Width != 0 && tw > 0 {
+		sx = float64(o.Width) / tw
+	}
+	if o.Height != 0 && th > 0 {
+		sy = float64(o.Height) / th
+	}
+	sin, cos := math.Sincos(float64(o.Rotation) * math.Pi / 180)
+
+	c := cloner{}
+	shapes := make([]*Object, 0, len(*td.Tile.ObjectLayer.Objects))
+	for _, shape := range *td.Tile.ObjectLayer.Objects {
+		s := c.object(shape)
+
+		// Tile local coordinates run down from the top of the tile, above the bottom-left origin of the Object
+		x, y := float64(shape.X)*sx, float64(shape.Y)*sy-th*sy
+		s.X = o.X + float32(x*cos-y*sin)
+		s.Y = o.Y + float32(x*sin+y*cos)
+		s.Width = float32(float64(shape.Width) * sx)
+		s.Height = float32(float64(shape.Height) * sy)
+		s.Rotation += o.Rotation
+
+		if s.Polygon, err = scalePoly(shape.Polygon, sx, sy); err != nil {
+			return nil, err
+		}
+		if s.Polyline, err = scalePoly(shape.Polyline, sx, sy); err != nil {
+			return nil, err
+		}
+		shapes = append(shapes, s)
+	}
+	return shapes, nil
 }
 
 // IsPoint returns true if the Object is a point, else false
@@ -110,10 +298,150 @@ func (o *Object) IsText() bool {
 	return o.Text != nil
 }
 
+// Kind returns the shape of the Object. Tile Objects are those with a GlobalID; Objects without a shape element are
+// rectangles.
+func (o *Object) Kind() ObjectKind {
+	switch {
+	case o.GlobalID != 0:
+		return KindTile
+	case o.IsPoint():
+		return KindPoint
+	case o.IsEllipse():
+		return KindEllipse
+	case o.IsPolygon():
+		return KindPolygon
+	case o.IsPolyline():
+		return KindPolyline
+	case o.IsText():
+		return KindText
+	default:
+		return KindRect
+	}
+}
+
+// EllipseContains returns true if the point lies within the ellipse inscribed in the Object bounds, boundary included.
+// The Object rotation is applied around its X, Y origin as in Tiled; an ellipse without width or height contains no
+// points.
+func (o *Object) EllipseContains(px, py float32) bool {
+	if o.Width <= 0 || o.Height <= 0 {
+		return false
+	}
+
+	// Rotate the point into the unrotated Object space
+	sin, cos := math.Sincos(-float64(o.Rotation) * math.Pi / 180)
+	dx, dy := float64(px-o.X), float64(py-o.Y)
+	x := dx*cos - dy*sin
+	y := dx*sin + dy*cos
+
+	rx, ry := float64(o.Width)/2, float64(o.Height)/2
+	nx, ny := (x-rx)/rx, (y-ry)/ry
+	return nx*nx+ny*ny <= 1
+}
+
+// EllipseBounds returns the smallest Rect of whole pixels containing the ellipse inscribed in the Object bounds, with
+// the Object rotation applied
+func (o *Object) EllipseBounds() Rect {
+	rx, ry := float64(o.Width)/2, float64(o.Height)/2
+	sin, cos := math.Sincos(float64(o.Rotation) * math.Pi / 180)
+
+	cx := float64(o.X) + rx*cos - ry*sin
+	cy := float64(o.Y) + rx*sin + ry*cos
+	hw := math.Sqrt(rx*rx*cos*cos + ry*ry*sin*sin)
+	hh := math.Sqrt(rx*rx*sin*sin + ry*ry*cos*cos)
+
+	// Allow for rounding error in the rotation so whole pixel edges do not grow by a pixel
+	const epsilon = 1e-9
+	return Rect{
+		Min: Point{int(math.Floor(cx - hw + epsilon)), int(math.Floor(cy - hh + epsilon))},
+		Max: Point{int(math.Ceil(cx + hw - epsilon)), int(math.Ceil(cy + hh - epsilon))},
+	}
+}
+
+// Bounds returns the axis aligned bounding box of the Object in map pixels, rounded out to whole pixels. Rotation turns
+// the Object about its origin: the bottom-left corner for tile Objects, which Tiled aligns by that corner, and the
+// top-left corner for every other Object. Ellipses are bound by EllipseBounds.
+func (o *Object) Bounds() Rect {
+	if o.IsEllipse() {
+		return o.EllipseBounds()
+	}
+
+	minX, minY, maxX, maxY := o.bounds()
+	// Allow for float32 rounding error in the rotation so whole pixel edges do not grow by a pixel
+	const epsilon = 1e-4
+	return Rect{
+		Min: Point{int(math.Floor(float64(minX) + epsilon)), int(math.Floor(float64(minY) + epsilon))},
+		Max: Point{int(math.Ceil(float64(maxX) - epsilon)), int(math.Ceil(float64(maxY) - epsilon))},
+	}
+}
+
+// intersects reports whether the Object bounds overlap the Rect, treating Rect.Max as exclusive
+func (o *Object) intersects(r Rect) bool {
+	minX, minY, maxX, maxY := o.bounds()
+	return overlaps(minX, maxX, float32(r.Min.X), float32(r.Max.X)) &&
+		overlaps(minY, maxY, float32(r.Min.Y), float32(r.Max.Y))
+}
+
+// overlaps reports whether [min, max] overlaps the half-open range [lo, hi); zero length ranges overlap when contained
+func overlaps(min, max, lo, hi float32) bool {
+	if min == max {
+		return min >= lo && min < hi
+	}
+	return min < hi && max > lo
+}
+
+// bounds returns the axis aligned bounding box of the Object in map coordinates
+func (o *Object) bounds() (minX, minY, maxX, maxY float32) {
+	var corners [][2]float64
+
+	var pts []PointF
+	switch {
+	case o.IsPolygon():
+		pts, _ = o.Polygon.PointsF()
+	case o.IsPolyline():
+		pts, _ = o.Polyline.PointsF()
+	}
+
+	switch {
+	case len(pts) > 0:
+		for _, p := range pts {
+			corners = append(corners, [2]float64{float64(p.X), float64(p.Y)})
+		}
+	case o.GlobalID != 0:
+		// Tile Objects are aligned by their bottom-left corner
+		w, h := float64(o.Width), float64(o.Height)
+		corners = [][2]float64{{0, -h}, {w, -h}, {w, 0}, {0, 0}}
+	default:
+		w, h := float64(o.Width), float64(o.Height)
+		corners = [][2]float64{{0, 0}, {w, 0}, {w, h}, {0, h}}
+	}
+
+	sin, cos := math.Sincos(float64(o.Rotation) * math.Pi / 180)
+	for i, c := range corners {
+		x := float32(c[0]*cos-c[1]*sin) + o.X
+		y := float32(c[0]*sin+c[1]*cos) + o.Y
+		if i == 0 || x < minX {
+			minX = x
+		}
+		if i == 0 || x > maxX {
+			maxX = x
+		}
+		if i == 0 || y < minY {
+			minY = y
+		}
+		if i == 0 || y > maxY {
+			maxY = y
+		}
+	}
+	return
+}
+
+// Text is the text of a text Object. Attributes omitted from the document take Tiled's defaults: sans-serif at 16px,
+// black, kerned.
 type Text struct {
 	FontFamily string     `xml:"fontfamily,attr"`
 	PixelSize  int        `xml:"pixelsize,attr"`
 	Wrap       bool       `xml:"wrap,attr"`
+	Color      string     `xml:"color,attr"`
 	Bold       bool       `xml:"bold,attr"`
 	Italic     bool       `xml:"italic,attr"`
 	Underline  bool       `xml:"underline,attr"`
@@ -129,6 +457,32 @@ type Point struct {
 	X, Y int
 }
 
+// Add returns the Point translated by o
+func (p Point) Add(o Point) Point {
+	return Point{p.X + o.X, p.Y + o.Y}
+}
+
+// Sub returns the Point translated by -o
+func (p Point) Sub(o Point) Point {
+	return Point{p.X - o.X, p.Y - o.Y}
+}
+
+// Scale returns the Point multiplied by f
+func (p Point) Scale(f int) Point {
+	return Point{p.X * f, p.Y * f}
+}
+
+// Equals returns true if the Point and o are the same coordinate
+func (p Point) Equals(o Point) bool {
+	return p == o
+}
+
+// PointF is an X, Y coordinate in space with fractional precision
+type PointF struct {
+	X float32 `json:"x"`
+	Y float32 `json:"y"`
+}
+
 // Poly represents a collection of points; used to represent a Polyline or a Polygon
 type Poly struct {
 	// Raw Points loaded from XML. Not intended to be used directly; use the
@@ -136,40 +490,95 @@ type Poly struct {
 	RawPoints string `xml:"points,attr"`
 }
 
-// Points returns a list of points in a Poly
+// scalePoly returns a copy of the Poly with its points scaled by sx and sy; `nil` for a `nil` Poly
+func scalePoly(p *Poly, sx, sy float64) (*Poly, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	pts, err := p.PointsF()
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]string, len(pts))
+	for i, pt := range pts {
+		raw[i] = strconv.FormatFloat(float64(pt.X)*sx, 'f', -1, 32) + "," +
+			strconv.FormatFloat(float64(pt.Y)*sy, 'f', -1, 32)
+	}
+	return &Poly{RawPoints: strings.Join(raw, " ")}, nil
+}
+
+// Points returns a list of points in a Poly, truncating fractional coordinates; use PointsF to keep them
 func (p *Poly) Points() (pts []Point, err error) {
-	for _, rpt := range strings.Split(p.RawPoints, " ") {
-		var x, y int64
+	fpts, err := p.PointsF()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fpt := range fpts {
+		pts = append(pts, Point{int(fpt.X), int(fpt.Y)})
+	}
+	return
+}
+
+// PointsF returns a list of float points in a Poly
+func (p *Poly) PointsF() (pts []PointF, err error) {
+	for _, rpt := range strings.Fields(p.RawPoints) {
+		var x, y float64
 
 		xy := strings.Split(rpt, ",")
 		if l := len(xy); l != 2 {
-			err = fmt.Errorf(
-				"unexpected number of coordinates in point destructure: %v in %v",
-				l, rpt,
+			return nil, fmt.Errorf(
+				"%w: unexpected number of coordinates in point destructure: %v in %q",
+				ErrDecodingPoly, l, rpt,
 			)
-
-			return
 		}
 
-		x, err = strconv.ParseInt(xy[0], 10, 32)
-		if err != nil {
-			return
+		if x, err = strconv.ParseFloat(xy[0], 32); err != nil {
+			return nil, fmt.Errorf("%w: invalid point %q: %w", ErrDecodingPoly, rpt, err)
 		}
-		y, err = strconv.ParseInt(xy[1], 10, 32)
-		if err != nil {
-			return
+		if y, err = strconv.ParseFloat(xy[1], 32); err != nil {
+			return nil, fmt.Errorf("%w: invalid point %q: %w", ErrDecodingPoly, rpt, err)
 		}
 
-		pts = append(pts, Point{int(x), int(y)})
+		pts = append(pts, PointF{float32(x), float32(y)})
 	}
 	return
 }
 
+// ColorRGBA returns the parsed Color of the ObjectLayer, or DefaultObjectColor when it is unset or invalid
+func (t *ObjectLayer) ColorRGBA() color.RGBA {
+	c, err := ParseColor(t.Color)
+	if err != nil {
+		return DefaultObjectColor
+	}
+	return c
+}
+
+// ColorRGBA returns the parsed Color of the Text
+func (t *Text) ColorRGBA() (color.RGBA, error) {
+	return ParseColor(t.Color)
+}
+
 type Template struct {
 	TileSet *Tileset `xml:"tileset"`
 	Object  *Object  `xml:"object"`
 }
 
+// ObjectKind is the shape of an Object; see (*Object).Kind
+type ObjectKind int
+
+const (
+	KindRect ObjectKind = iota
+	KindPoint
+	KindEllipse
+	KindPolygon
+	KindPolyline
+	KindText
+	KindTile
+)
+
 type DrawOrder int
 
 const (
@@ -196,20 +605,23 @@ const (
 
 func (t *ObjectLayer) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 	type tmpObjectLayer ObjectLayer
-	var tmp tmpObjectLayer
+	// Like Tiled, layers are visible and fully opaque unless the document says otherwise
+	tmp := tmpObjectLayer{Opacity: 1, Visible: true}
 
 	if err := xd.DecodeElement(&tmp, &start); err != nil {
 		return fmt.Errorf("%w: %w", ErrDecodingObjectLayer, err)
 	}
 
 	*t = (ObjectLayer)(tmp)
+	t.offset = xd.InputOffset()
 
 	return nil
 }
 
 func (o *Object) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 	type tmpObject Object
-	var tmp tmpObject
+	// Like Tiled, Objects are visible unless the document says otherwise
+	tmp := tmpObject{Visible: true}
 
 	if err := xd.DecodeElement(&tmp, &start); err != nil {
 		return fmt.Errorf("%w: %w", ErrDecodingTileLayer, err)
@@ -217,76 +629,135 @@ func (o *Object) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 
 	*o = (Object)(tmp)
 
-	if tmp.Template == "" {
+	hasVisible := slices.ContainsFunc(start.Attr, func(a xml.Attr) bool { return a.Name.Local == "visible" })
+	return o.finishDecode(hasVisible)
+}
+
+// finishDecode completes an Object read from either format, merging in its template unless decoding with
+// SkipExternalResolve; an instance that doesn't declare its visibility takes that of the template
+func (o *Object) finishDecode(hasVisible bool) error {
+	o.project = decodeOptions.project
+	// Normalise once any template has been merged
+	defer o.normalizeClass()
+
+	if o.Template == "" || decodeOptions.skipExternal {
 		return nil
 	}
 
-	path := filepath.Join(ResourcePath, tmp.Template)
-	f, err := os.Open(path)
+	// The size the instance declares itself, before any is inherited
+	width, height := o.Width, o.Height
+
+	path := filepath.Join(ResourcePath, o.Template)
+	template, err := loadTemplate(path)
 	if err != nil {
-		return fmt.Errorf("failed to open template file: %w", err)
+		return err
 	}
-	defer func(f *os.File) {
-		err := f.Close()
-		if err != nil {
-			fmt.Printf("error closing template file handler %s", errors.Unwrap(err))
-		}
-	}(f)
-
-	var template Template
-	if err := xml.NewDecoder(f).Decode(&template); err != nil {
-		return fmt.Errorf("%w: %w", ErrDecodingTemplate, err)
+	if template.Object == nil {
+		return nil
 	}
+	to := template.Object
 
 	if o.Name == "" {
-		o.Name = tmp.Name
+		o.Name = to.Name
 	}
 	if o.Type == "" {
-		o.Type = tmp.Type
+		o.Type = to.Type
+	}
+	if o.Class == "" {
+		o.Class = to.Class
 	}
 	if o.X == 0 {
-		o.X = tmp.X
+		o.X = to.X
 	}
 	if o.Y == 0 {
-		o.Y = tmp.Y
+		o.Y = to.Y
 	}
 	if o.Width == 0 {
-		o.Width = tmp.Width
+		o.Width = to.Width
 	}
 	if o.Height == 0 {
-		o.Height = tmp.Height
+		o.Height = to.Height
 	}
 	if o.Rotation == 0 {
-		o.Rotation = tmp.Rotation
+		o.Rotation = to.Rotation
 	}
-	if !o.Visible {
-		o.Visible = tmp.Visible
+	if !hasVisible {
+		o.Visible = to.Visible
 	}
-	if o.GlobalID == 0 {
-		o.GlobalID = tmp.GlobalID
+	if o.GlobalID == 0 && to.GlobalID != 0 {
+		o.GlobalID = to.GlobalID
+		// The GlobalID counts from the template Tileset, which the Map must share for it to resolve
+		if template.TileSet != nil {
+			o.templatePath, o.templateTileset = path, template.TileSet.sourcePath()
+			o.templateFirstGID = template.TileSet.FirstGlobalID
+		}
 	}
 	if o.Properties == nil {
-		o.Properties = tmp.Properties
+		o.Properties = to.Properties
 	}
 	if o.Image == nil {
-		o.Image = tmp.Image
+		o.Image = to.Image
 	}
-	if o.Polygon == nil {
-		o.Polygon = tmp.Polygon
+	// The shape is inherited whole, and only by an instance without a shape of its own; a sized instance is a
+	// rectangle and can't become a point
+	if !o.hasShape() && !(to.IsPoint() && (width != 0 || height != 0)) {
+		o.Polygon = to.Polygon
+		o.Polyline = to.Polyline
+		o.Text = to.Text
+		o.Ellipse = to.Ellipse
+		o.Point = to.Point
 	}
-	if o.Polygon == nil {
-		o.Polyline = tmp.Polyline
+
+	return nil
+}
+
+// hasShape reports whether the Object declares a shape element, so isn't a plain rectangle or tile
+func (o *Object) hasShape() bool {
+	return o.IsPoint() || o.IsEllipse() || o.IsPolygon() || o.IsPolyline() || o.IsText()
+}
+
+// normalizeClass fills whichever of Type and Class is empty from the other; Tiled 1.9 renamed the Object `type`
+// attribute to `class`, so maps from either side of the change read the same
+func (o *Object) normalizeClass() {
+	switch {
+	case o.Class == "":
+		o.Class = o.Type
+	case o.Type == "":
+		o.Type = o.Class
 	}
-	if o.Text == nil {
-		o.Text = tmp.Text
+}
+
+// loadTemplate decodes the object template at path, resolving its Tileset source relative to the template
+func loadTemplate(path string) (*Template, error) {
+	buf, err := readResource(path, "template")
+	if err != nil {
+		return nil, &ResourceError{Path: path, Err: err}
 	}
-	if o.Ellipse == nil {
-		o.Ellipse = tmp.Ellipse
+
+	resourcePath := ResourcePath
+	ResourcePath = filepath.Dir(path)
+	defer func() {
+		ResourcePath = resourcePath
+	}()
+
+	var template Template
+	if err := xml.Unmarshal(buf, &template); err != nil {
+		return nil, &ResourceError{Path: path, Err: fmt.Errorf("%w: %w", ErrDecodingTemplate, err)}
 	}
-	if o.Point == nil {
-		o.Point = tmp.Point
+	return &template, nil
+}
+
+func (t *Text) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
+	type tmpText Text
+	// Attributes omitted from the document take Tiled's defaults; the zero HAlign and VAlign are left and top
+	tmp := tmpText{FontFamily: "sans-serif", PixelSize: 16, Color: "#000000", Kerning: true}
+
+	if err := xd.DecodeElement(&tmp, &start); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingText, err)
 	}
 
+	*t = (Text)(tmp)
+
 	return nil
 }
 
@@ -294,7 +765,8 @@ func (d *DrawOrder) UnmarshalText(text []byte) error {
 	s := strings.ToLower(string(text))
 	switch strings.ToLower(s) {
 	default:
-		return fmt.Errorf("%w: %s", ErrUnknownDrawOrder, s)
+		*d = TopDown
+		return unknownEnum(ErrUnknownDrawOrder, s)
 	case "":
 		*d = TopDown
 	case "topdown":
@@ -305,11 +777,22 @@ func (d *DrawOrder) UnmarshalText(text []byte) error {
 	return nil
 }
 
+func (d DrawOrder) MarshalText() ([]byte, error) {
+	switch d {
+	case TopDown:
+		return []byte("topdown"), nil
+	case Index:
+		return []byte("index"), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownDrawOrder, d)
+}
+
 func (o *HAlignment) UnmarshalText(text []byte) error {
 	s := strings.ToLower(string(text))
 	switch strings.ToLower(s) {
 	default:
-		return fmt.Errorf("%w: %s", ErrUnknownHAlignment, s)
+		*o = HLeft
+		return unknownEnum(ErrUnknownHAlignment, s)
 	case "":
 		*o = HLeft
 	case "left":
@@ -324,11 +807,26 @@ func (o *HAlignment) UnmarshalText(text []byte) error {
 	return nil
 }
 
+func (o HAlignment) MarshalText() ([]byte, error) {
+	switch o {
+	case HLeft:
+		return []byte("left"), nil
+	case HCenter:
+		return []byte("center"), nil
+	case HRight:
+		return []byte("right"), nil
+	case HJustify:
+		return []byte("justify"), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownHAlignment, o)
+}
+
 func (o *VAlignment) UnmarshalText(text []byte) error {
 	s := strings.ToLower(string(text))
 	switch strings.ToLower(s) {
 	default:
-		return fmt.Errorf("%w: %s", ErrUnknownVAlignment, s)
+		*o = VTop
+		return unknownEnum(ErrUnknownVAlignment, s)
 	case "":
 		*o = VTop
 	case "top":
@@ -340,3 +838,15 @@ func (o *VAlignment) UnmarshalText(text []byte) error {
 	}
 	return nil
 }
+
+func (o VAlignment) MarshalText() ([]byte, error) {
+	switch o {
+	case VTop:
+		return []byte("top"), nil
+	case VCenter:
+		return []byte("center"), nil
+	case VBottom:
+		return []byte("bottom"), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownVAlignment, o)
+}
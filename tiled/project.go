@@ -0,0 +1,205 @@
+package tiled
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Project is a Tiled project file (.tiled-project), declaring the custom class and enum types used by Properties
+// https://doc.mapeditor.org/en/stable/manual/projects/
+type Project struct {
+	CompatibilityVersion int           `json:"compatibilityVersion"`
+	ExtensionsPath       string        `json:"extensionsPath"`
+	AutomappingRules     string        `json:"automappingRulesFile"`
+	Folders              []string      `json:"folders"`
+	PropertyTypes        []*CustomType `json:"propertyTypes"`
+}
+
+// CustomType is a custom property type declared in a Project; either a class with Members or an enum with Values
+type CustomType struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Type is either `class` or `enum`
+	Type string `json:"type"`
+
+	// Class types
+	Color    string         `json:"color"`
+	DrawFill bool           `json:"drawFill"`
+	Members  []*ClassMember `json:"members"`
+	UseAs    []string       `json:"useAs"`
+
+	// Enum types
+	StorageType   string   `json:"storageType"`
+	Values        []string `json:"values"`
+	ValuesAsFlags bool     `json:"valuesAsFlags"`
+}
+
+// ClassMember is a member of a class CustomType and its default value
+type ClassMember struct {
+	Name         string          `json:"name"`
+	Type         string          `json:"type"`
+	PropertyType string          `json:"propertyType"`
+	Value        json.RawMessage `json:"value"`
+}
+
+// LoadProject returns a Project from the given path
+func LoadProject(path string) (*Project, error) {
+	if path == "" {
+		return nil, errors.New("file path is empty")
+	}
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project file: %w", err)
+	}
+
+	var p Project
+	if err := json.Unmarshal(buf, &p); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecodingProject, err)
+	}
+	return &p, nil
+}
+
+// Classes returns the class CustomTypes declared in the Project
+func (p *Project) Classes() []*CustomType {
+	return p.typesOf("class")
+}
+
+// Enums returns the enum CustomTypes declared in the Project
+func (p *Project) Enums() []*CustomType {
+	return p.typesOf("enum")
+}
+
+func (p *Project) typesOf(kind string) []*CustomType {
+	var types []*CustomType
+	for _, ct := range p.PropertyTypes {
+		if ct.Type == kind {
+			types = append(types, ct)
+		}
+	}
+	return types
+}
+
+// ClassWithName retrieves the class CustomType matching the provided name. Returns `nil` if not found.
+func (p *Project) ClassWithName(name string) *CustomType {
+	for _, ct := range p.Classes() {
+		if ct.Name == name {
+			return ct
+		}
+	}
+	return nil
+}
+
+// EnumWithName retrieves the enum CustomType matching the provided name. Returns `nil` if not found.
+func (p *Project) EnumWithName(name string) *CustomType {
+	for _, ct := range p.Enums() {
+		if ct.Name == name {
+			return ct
+		}
+	}
+	return nil
+}
+
+// ClassDefaults returns the default Properties declared by the members of the named class. Returns `nil` if the class
+// is not declared.
+func (p *Project) ClassDefaults(name string) (*Properties, error) {
+	ct := p.ClassWithName(name)
+	if ct == nil {
+		return nil, nil
+	}
+
+	var defaults Properties
+	for _, m := range ct.Members {
+		prop, err := p.memberProperty(m)
+		if err != nil {
+			return nil, fmt.Errorf("%w: class %s member %s: %w", ErrDecodingProject, name, m.Name, err)
+		}
+		defaults = append(defaults, prop)
+	}
+	return &defaults, nil
+}
+
+// RegisterClasses registers the defaults of every class declared in the Project with RegisterClass
+func (p *Project) RegisterClasses() error {
+	for _, ct := range p.Classes() {
+		defaults, err := p.ClassDefaults(ct.Name)
+		if err != nil {
+			return err
+		}
+		RegisterClass(ct.Name, defaults)
+	}
+	return nil
+}
+
+func (p *Project) memberProperty(m *ClassMember) (*Property, error) {
+	prop := &Property{Name: m.Name, CustomType: m.PropertyType}
+	if err := prop.Type.UnmarshalText([]byte(m.Type)); err != nil {
+		return nil, err
+	}
+
+	if prop.Type == Class {
+		nested, err := p.ClassDefaults(m.PropertyType)
+		if err != nil {
+			return nil, err
+		}
+
+		var values map[string]json.RawMessage
+		if len(m.Value) > 0 {
+			if err := json.Unmarshal(m.Value, &values); err != nil {
+				return nil, err
+			}
+		}
+		if nested != nil {
+			for _, np := range *nested {
+				v, ok := values[np.Name]
+				if !ok {
+					continue
+				}
+				if np.Value, err = jsonScalar(v); err != nil {
+					return nil, err
+				}
+			}
+		}
+		prop.Properties = nested
+		return prop, nil
+	}
+
+	v, err := jsonScalar(m.Value)
+	if err != nil {
+		return nil, err
+	}
+	prop.Value = v
+	return prop, nil
+}
+
+// jsonScalar converts a JSON string, number or boolean to its TMX attribute representation
+func jsonScalar(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", err
+	}
+	return scalarString(v)
+}
+
+// scalarString converts a decoded JSON string, number or boolean to its TMX attribute representation
+func scalarString(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
@@ -1,9 +1,12 @@
 package tiled
 
 import (
+	"encoding/xml"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // Properties is an array of Property Objects
@@ -19,6 +22,75 @@ func (pl Properties) WithName(name string) *Property {
 	return nil
 }
 
+// Merge returns the Properties with any Property of defaults not already present appended, keeping the order of the
+// defaults. Neither Properties is modified.
+func (pl Properties) Merge(defaults Properties) Properties {
+	var merged Properties
+	for _, d := range defaults {
+		if p := pl.WithName(d.Name); p != nil {
+			merged = append(merged, p)
+			continue
+		}
+		merged = append(merged, d)
+	}
+	for _, p := range pl {
+		if defaults.WithName(p.Name) == nil {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// classes holds the default Properties of registered classes
+var (
+	classes   = map[string]*Properties{}
+	classesMu sync.RWMutex
+)
+
+// RegisterClass registers the default Properties of a class, inherited by Objects of that class through
+// (*Object).EffectiveProperties. Registering a class again replaces its defaults; nil defaults unregister it.
+func RegisterClass(name string, defaults *Properties) {
+	classesMu.Lock()
+	defer classesMu.Unlock()
+
+	if defaults == nil {
+		delete(classes, name)
+		return
+	}
+	classes[name] = defaults
+}
+
+// classDefaults returns the registered default Properties of a class, nil if not registered
+func classDefaults(name string) *Properties {
+	classesMu.RLock()
+	defer classesMu.RUnlock()
+
+	return classes[name]
+}
+
+// inheritClass returns props merged over the defaults of the named class, declared by proj or, with a `nil` Project,
+// registered with RegisterClass. Returns props when the class is unknown or its defaults fail to resolve.
+func inheritClass(props *Properties, class string, proj *Project) *Properties {
+	if class == "" {
+		return props
+	}
+
+	defaults := classDefaults(class)
+	if proj != nil {
+		defaults, _ = proj.ClassDefaults(class)
+	}
+	if defaults == nil {
+		return props
+	}
+
+	var own Properties
+	if props != nil {
+		own = *props
+	}
+	merged := own.Merge(*defaults)
+	return &merged
+}
+
 // Property wraps any number of custom Properties, and is used as a child of a
 // number of other Objects.
 type Property struct {
@@ -29,6 +101,38 @@ type Property struct {
 	InnerValue string       `xml:",chardata"`
 
 	Properties *Properties `xml:"properties>property"`
+
+	// Unrecognised attributes and elements, kept so they can be written back out
+	UnknownAttrs    []xml.Attr       `xml:",any,attr"`
+	UnknownElements []UnknownElement `xml:",any"`
+}
+
+// UnmarshalXML decodes a Property and normalises its value, which Tiled stores either in the value attribute, with
+// newlines escaped as `\n`, or as multi-line character data. Value and InnerValue both hold the unescaped value
+// wherever it was stored; Properties with nested Properties keep their character data with the indentation around the
+// nested Properties trimmed.
+func (p *Property) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
+	type tmpProperty Property
+	var tmp tmpProperty
+
+	if err := xd.DecodeElement(&tmp, &start); err != nil {
+		return fmt.Errorf("%w: %w", ErrDecodingProperty, err)
+	}
+
+	*p = (Property)(tmp)
+	if p.Properties != nil {
+		p.InnerValue = strings.TrimSpace(p.InnerValue)
+		return nil
+	}
+
+	switch {
+	case p.Value == "" && p.Type == String:
+		p.Value = p.InnerValue
+	case p.Value == "":
+		p.Value = strings.TrimSpace(p.InnerValue)
+	}
+	p.InnerValue = p.Value
+	return nil
 }
 
 // Float returns a value from a given float Property
@@ -66,6 +170,93 @@ func (p Property) Bool() (v bool, err error) {
 	return p.Value == "true", nil
 }
 
+// ObjectID returns the referenced ObjectID from a given object Property; use (*Map).ObjectByID to resolve it
+func (p Property) ObjectID() (v ObjectID, err error) {
+	if p.Type != Obj {
+		return v, fmt.Errorf("%w: object", ErrPropertyWrongType)
+	}
+
+	id, err := strconv.ParseUint(p.Value, 10, 32)
+	if err != nil {
+		return v, fmt.Errorf("%w: %w", ErrPropertyFailedConversion, err)
+	}
+
+	return ObjectID(id), nil
+}
+
+// EnumValues returns the names of the values of a given enum Property, validated against the enum declared in the
+// Project. Flag enums may hold any number of values; other enums hold exactly one.
+func (p Property) EnumValues(proj *Project) ([]string, error) {
+	var enum *CustomType
+	if proj != nil {
+		enum = proj.EnumWithName(p.CustomType)
+	}
+	if enum == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCustomType, p.CustomType)
+	}
+
+	if enum.StorageType == "int" {
+		if p.Type != Int {
+			return nil, fmt.Errorf("%w: int", ErrPropertyWrongType)
+		}
+
+		v, err := strconv.ParseUint(p.Value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrPropertyFailedConversion, err)
+		}
+
+		if !enum.ValuesAsFlags {
+			if v >= uint64(len(enum.Values)) {
+				return nil, fmt.Errorf("%w: %d is not a value of enum %s", ErrPropertyFailedConversion, v, enum.Name)
+			}
+			return []string{enum.Values[v]}, nil
+		}
+
+		var values []string
+		for i := 0; v != 0; i, v = i+1, v>>1 {
+			if v&1 == 0 {
+				continue
+			}
+			if i >= len(enum.Values) {
+				return nil, fmt.Errorf("%w: flag %d is not a value of enum %s", ErrPropertyFailedConversion, i, enum.Name)
+			}
+			values = append(values, enum.Values[i])
+		}
+		return values, nil
+	}
+
+	if p.Type != String {
+		return nil, fmt.Errorf("%w: string", ErrPropertyWrongType)
+	}
+
+	values := []string{p.Value}
+	if enum.ValuesAsFlags {
+		values = nil
+		if p.Value != "" {
+			values = strings.Split(p.Value, ",")
+		}
+	}
+	for _, v := range values {
+		if !slices.Contains(enum.Values, v) {
+			return nil, fmt.Errorf("%w: %q is not a value of enum %s", ErrPropertyFailedConversion, v, enum.Name)
+		}
+	}
+	return values, nil
+}
+
+// EnumValue returns the name of the value of a given single value enum Property, validated against the enum declared in
+// the Project
+func (p Property) EnumValue(proj *Project) (string, error) {
+	values, err := p.EnumValues(proj)
+	if err != nil {
+		return "", err
+	}
+	if len(values) != 1 {
+		return "", fmt.Errorf("%w: expected a single enum value, got %d", ErrPropertyFailedConversion, len(values))
+	}
+	return values[0], nil
+}
+
 type PropertyType int
 
 const (
@@ -83,7 +274,8 @@ func (r *PropertyType) UnmarshalText(text []byte) error {
 	s := strings.ToLower(string(text))
 	switch strings.ToLower(s) {
 	default:
-		return fmt.Errorf("%w: %s", ErrUnknownPropertyType, s)
+		*r = String
+		return unknownEnum(ErrUnknownPropertyType, s)
 	case "string":
 		*r = String
 	case "int":
@@ -103,3 +295,25 @@ func (r *PropertyType) UnmarshalText(text []byte) error {
 	}
 	return nil
 }
+
+func (r PropertyType) MarshalText() ([]byte, error) {
+	switch r {
+	case String:
+		return []byte("string"), nil
+	case Int:
+		return []byte("int"), nil
+	case Float:
+		return []byte("float"), nil
+	case Bool:
+		return []byte("bool"), nil
+	case Color:
+		return []byte("color"), nil
+	case File:
+		return []byte("file"), nil
+	case Obj:
+		return []byte("object"), nil
+	case Class:
+		return []byte("class"), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownPropertyType, r)
+}
@@ -0,0 +1,184 @@
+package tiled
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteTemplate writes the Template as a Tiled object template (.tx) https://doc.mapeditor.org/en/stable/reference/tmx-map-format/#template
+// The Template Tileset is written as a reference to its Source file; as Tiled only references external Tilesets from
+// templates, an embedded Tileset, one without a Source, is an error.
+func WriteTemplate(w io.Writer, t *Template) error {
+	xt := xmlTemplate{}
+	if t.TileSet != nil {
+		if t.TileSet.Source == "" {
+			return fmt.Errorf("%w: %q", ErrTemplateTilesetEmbedded, t.TileSet.Name)
+		}
+		xt.TileSet = &xmlTilesetRef{FirstGlobalID: t.TileSet.FirstGlobalID, Source: t.TileSet.Source}
+	}
+	if t.Object != nil {
+		xt.Object = xmlObjectOf(t.Object)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(xt); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type xmlTemplate struct {
+	XMLName xml.Name       `xml:"template"`
+	TileSet *xmlTilesetRef `xml:"tileset"`
+	Object  *xmlObject     `xml:"object"`
+}
+
+type xmlTilesetRef struct {
+	FirstGlobalID GlobalID `xml:"firstgid,attr"`
+	Source        string   `xml:"source,attr"`
+}
+
+type xmlObject struct {
+	ObjectID   ObjectID       `xml:"id,attr,omitempty"`
+	Name       string         `xml:"name,attr,omitempty"`
+	Type       string         `xml:"type,attr,omitempty"`
+	Class      string         `xml:"class,attr,omitempty"`
+	GlobalID   GlobalID       `xml:"gid,attr,omitempty"`
+	X          float32        `xml:"x,attr,omitempty"`
+	Y          float32        `xml:"y,attr,omitempty"`
+	Width      float32        `xml:"width,attr,omitempty"`
+	Height     float32        `xml:"height,attr,omitempty"`
+	Rotation   float32        `xml:"rotation,attr,omitempty"`
+	Visible    int            `xml:"visible,attr"`
+	Template   string         `xml:"template,attr,omitempty"`
+	Properties *xmlProperties `xml:"properties"`
+	Ellipse    *struct{}      `xml:"ellipse"`
+	Point      *struct{}      `xml:"point"`
+	Polygon    *xmlPoly       `xml:"polygon"`
+	Polyline   *xmlPoly       `xml:"polyline"`
+	Text       *xmlText       `xml:"text"`
+}
+
+type xmlPoly struct {
+	Points string `xml:"points,attr"`
+}
+
+type xmlText struct {
+	FontFamily string     `xml:"fontfamily,attr"`
+	PixelSize  int        `xml:"pixelsize,attr"`
+	Wrap       int        `xml:"wrap,attr"`
+	Color      string     `xml:"color,attr"`
+	Bold       int        `xml:"bold,attr"`
+	Italic     int        `xml:"italic,attr"`
+	Underline  int        `xml:"underline,attr"`
+	Strikeout  int        `xml:"strikeout,attr"`
+	Kerning    int        `xml:"kerning,attr"`
+	HAlign     HAlignment `xml:"halign,attr"`
+	VAlign     VAlignment `xml:"valign,attr"`
+	Value      string     `xml:",chardata"`
+}
+
+type xmlProperties struct {
+	Properties []*xmlProperty `xml:"property"`
+}
+
+type xmlProperty struct {
+	Name         string         `xml:"name,attr"`
+	Type         *PropertyType  `xml:"type,attr,omitempty"`
+	PropertyType string         `xml:"propertytype,attr,omitempty"`
+	Value        string         `xml:"value,attr,omitempty"`
+	InnerValue   string         `xml:",chardata"`
+	Properties   *xmlProperties `xml:"properties"`
+}
+
+func xmlObjectOf(o *Object) *xmlObject {
+	xo := &xmlObject{
+		ObjectID:   o.ObjectID,
+		Name:       o.Name,
+		Type:       o.Type,
+		Class:      o.Class,
+		GlobalID:   o.GlobalID,
+		X:          o.X,
+		Y:          o.Y,
+		Width:      o.Width,
+		Height:     o.Height,
+		Rotation:   o.Rotation,
+		Visible:    xmlBool(o.Visible),
+		Template:   o.Template,
+		Properties: xmlPropertiesOf(o.Properties),
+		Ellipse:    o.Ellipse,
+		Point:      o.Point,
+	}
+	// Decoding fills Type from Class, so only a differing legacy Type is written
+	if o.Type == o.Class {
+		xo.Type = ""
+	}
+	if o.Polygon != nil {
+		xo.Polygon = &xmlPoly{Points: o.Polygon.RawPoints}
+	}
+	if o.Polyline != nil {
+		xo.Polyline = &xmlPoly{Points: o.Polyline.RawPoints}
+	}
+	if o.Text != nil {
+		xo.Text = &xmlText{
+			FontFamily: o.Text.FontFamily,
+			PixelSize:  o.Text.PixelSize,
+			Wrap:       xmlBool(o.Text.Wrap),
+			Color:      o.Text.Color,
+			Bold:       xmlBool(o.Text.Bold),
+			Italic:     xmlBool(o.Text.Italic),
+			Underline:  xmlBool(o.Text.Underline),
+			Strikeout:  xmlBool(o.Text.Strikeout),
+			Kerning:    xmlBool(o.Text.Kerning),
+			HAlign:     o.Text.HAlign,
+			VAlign:     o.Text.VAlign,
+			Value:      o.Text.Value,
+		}
+	}
+	return xo
+}
+
+func xmlPropertiesOf(pl *Properties) *xmlProperties {
+	if pl == nil {
+		return nil
+	}
+
+	xps := &xmlProperties{}
+	for _, p := range *pl {
+		xp := &xmlProperty{
+			Name:         p.Name,
+			PropertyType: p.CustomType,
+			Value:        p.Value,
+			Properties:   xmlPropertiesOf(p.Properties),
+		}
+		// String is the default type and is left implicit, as Tiled does
+		if p.Type != String {
+			pt := p.Type
+			xp.Type = &pt
+		}
+		if p.Value == "" && xp.Properties == nil {
+			xp.InnerValue = strings.TrimSpace(p.InnerValue)
+		}
+		// Multi-line values are written as character data, as Tiled does
+		if strings.Contains(p.Value, "\n") && xp.Properties == nil {
+			xp.Value, xp.InnerValue = "", p.Value
+		}
+		xps.Properties = append(xps.Properties, xp)
+	}
+	return xps
+}
+
+// xmlBool returns the TMX representation of a boolean attribute
+func xmlBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -1,43 +1,264 @@
 package tiled
 
 import (
+	"bytes"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 )
 
 var ResourcePath = ""
 
-// New returns a Map from the given path
-func New(path string) (*Map, error) {
+// UnknownElement is an XML element not recognised by the package, kept so it can be written back out
+type UnknownElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
+}
+
+// Option configures how a Map is decoded
+type Option func(*options)
+
+type options struct {
+	keepLayerData bool
+	lenientEnums  bool
+	headerOnly    bool
+	skipExternal  bool
+	opener        ResourceOpener
+	project       *Project
+}
+
+// ResourceOpener opens the files a Map is loaded from: the map itself and the external Tilesets and templates it
+// references, named relative to the directory of the map
+type ResourceOpener interface {
+	Open(name string) (io.ReadCloser, error)
+}
+
+type osOpener struct{}
+
+func (osOpener) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+// decodeOptions holds the Options of the Map currently being decoded and decodeWarnings the warnings collected while
+// decoding it; decodeMu serialises access while decoding
+var (
+	decodeOptions  options
+	decodeWarnings []string
+	decodeMu       sync.Mutex
+)
+
+// KeepLayerData keeps a normalised copy of each TileLayer payload in TileLayer.LayerData after decoding
+func KeepLayerData() Option {
+	return func(o *options) {
+		o.keepLayerData = true
+	}
+}
+
+// LenientEnums makes unknown enum attribute values, such as an unknown orientation, fall back to their default and
+// record a warning instead of failing the load
+func LenientEnums(lenient bool) Option {
+	return func(o *options) {
+		o.lenientEnums = lenient
+	}
+}
+
+// HeaderOnly skips decoding TileLayer data, leaving TileGlobalRefs and TileDefs empty; the Map attributes, Properties,
+// Tilesets and layer attributes are still decoded
+func HeaderOnly() Option {
+	return func(o *options) {
+		o.headerOnly = true
+	}
+}
+
+// SkipExternalResolve leaves external Tilesets and Object templates as stubs holding only their FirstGlobalID and
+// Source, or Template, instead of opening the files they reference
+func SkipExternalResolve(skip bool) Option {
+	return func(o *options) {
+		o.skipExternal = skip
+	}
+}
+
+// WithOpener opens the map, Tilesets and templates through the ResourceOpener instead of the OS file system
+func WithOpener(r ResourceOpener) Option {
+	return func(o *options) {
+		o.opener = r
+	}
+}
+
+// WithProject loads the Map against a Project, kept in Map.Project; Objects of the Map inherit the defaults of the
+// Project classes instead of those registered with RegisterClass
+func WithProject(p *Project) Option {
+	return func(o *options) {
+		o.project = p
+	}
+}
+
+// openResource opens a file through the ResourceOpener of the Map being decoded
+func openResource(name string) (io.ReadCloser, error) {
+	if decodeOptions.opener == nil {
+		return osOpener{}.Open(name)
+	}
+	return decodeOptions.opener.Open(name)
+}
+
+// warn records a non-fatal problem found while decoding
+func warn(format string, a ...any) {
+	decodeWarnings = append(decodeWarnings, fmt.Sprintf(format, a...))
+}
+
+// unknownEnum reports an unknown enum value; an error by default, or a warning when decoding with LenientEnums
+func unknownEnum(err error, s string) error {
+	if decodeOptions.lenientEnums {
+		warn("%s: %s", err, s)
+		return nil
+	}
+	return fmt.Errorf("%w: %s", err, s)
+}
+
+// NewHeader returns a Map from the given path without decoding its TileLayer data; see HeaderOnly
+func NewHeader(path string, opts ...Option) (*Map, error) {
+	return New(path, append(opts, HeaderOnly())...)
+}
+
+// New returns a Map from the given TMX (.tmx) or JSON (.tmj or .json) path
+func New(path string, opts ...Option) (*Map, error) {
 	if path == "" {
 		return nil, errors.New("file path is empty")
 	}
 
-	f, err := os.Open(path)
+	decodeMu.Lock()
+	defer decodeMu.Unlock()
+
+	applyOptions(opts)
+	defer resetDecode()
+
+	buf, err := readResource(path, "map")
 	if err != nil {
-		return nil, fmt.Errorf("failed to open map file: %w", err)
+		return nil, err
 	}
-	defer func(f *os.File) {
+
+	ResourcePath = filepath.Dir(path)
+	var m Map
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".tmj" || ext == ".json" {
+		err = m.decodeJSON(buf)
+	} else {
+		err = xml.Unmarshal(buf, (*decodingMap)(&m))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse map file: %w", err)
+	}
+	m.Project = decodeOptions.project
+	m.options = decodeOptions
+	return &m, nil
+}
+
+// LoadTileset returns a Tileset from the given external tileset (.tsx) path, independently of any Map. A Tileset
+// without an image of its own is lent the image of its first Tile that has one, as when loaded through a Map.
+func LoadTileset(path string, opts ...Option) (*Tileset, error) {
+	if path == "" {
+		return nil, errors.New("file path is empty")
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".tsj" || ext == ".json" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+
+	decodeMu.Lock()
+	defer decodeMu.Unlock()
+
+	applyOptions(opts)
+	// Tilesets have nowhere to keep warnings
+	defer resetDecode()
+
+	buf, err := readResource(path, "Tileset")
+	if err != nil {
+		return nil, err
+	}
+
+	ResourcePath = filepath.Dir(path)
+	var ts Tileset
+	if err := xml.Unmarshal(buf, &ts); err != nil {
+		return nil, fmt.Errorf("failed to parse Tileset file: %w", err)
+	}
+	if err := ts.useTileImage(); err != nil {
+		return nil, err
+	}
+	ts.path = path
+	return &ts, nil
+}
+
+// LoadTemplate returns a Template from the given object template (.tx) path, independently of any Map. The
+// Template Tileset is loaded from its source relative to the template file.
+func LoadTemplate(path string, opts ...Option) (*Template, error) {
+	if path == "" {
+		return nil, errors.New("file path is empty")
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".tj" || ext == ".json" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+
+	decodeMu.Lock()
+	defer decodeMu.Unlock()
+
+	applyOptions(opts)
+	defer resetDecode()
+
+	return loadTemplate(path)
+}
+
+// applyOptions resets decodeOptions to the given Options; callers must hold decodeMu
+func applyOptions(opts []Option) {
+	decodeOptions = options{}
+	for _, opt := range opts {
+		opt(&decodeOptions)
+	}
+}
+
+// resetDecode clears the Options and warnings left by a load, so later decoding, including a plain xml.Unmarshal, starts
+// from the zero Options; callers must hold decodeMu
+func resetDecode() {
+	decodeOptions, decodeWarnings = options{}, nil
+}
+
+// lockDecode takes decodeMu for a Map decoded outside New, such as by xml.Unmarshal, so it can't interleave with a load
+// in progress; decoding starts from the zero Options, and the returned func clears the decode state and unlocks
+func lockDecode() func() {
+	decodeMu.Lock()
+	resetDecode()
+	return func() {
+		resetDecode()
+		decodeMu.Unlock()
+	}
+}
+
+// readResource reads the whole of a file opened through openResource; kind names the file in errors
+func readResource(path, kind string) ([]byte, error) {
+	f, err := openResource(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s file: %w", kind, err)
+	}
+	defer func(f io.ReadCloser) {
 		err := f.Close()
 		if err != nil {
-			fmt.Printf("error closing map file handler %s", errors.Unwrap(err))
+			fmt.Printf("error closing %s file handler %s", kind, errors.Unwrap(err))
 		}
 	}(f)
 
 	buf, err := io.ReadAll(f)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read map file: %w", err)
+		return nil, fmt.Errorf("failed to read %s file: %w", kind, err)
 	}
+	return trimPreamble(buf), nil
+}
 
-	ResourcePath = filepath.Dir(path)
-	var m Map
-	err = xml.Unmarshal(buf, &m)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse map file: %w", err)
-	}
-	return &m, nil
+// trimPreamble strips a leading UTF-8 byte order mark and whitespace, which editors on Windows and hand edits leave
+// before the XML declaration, so documents reach the decoder starting at their first element or declaration
+func trimPreamble(buf []byte) []byte {
+	buf = bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf"))
+	return bytes.TrimLeft(buf, " \t\r\n")
 }
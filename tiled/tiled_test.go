@@ -1,12 +1,31 @@
 package tiled_test
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"github.com/dwaynedwards/go-tiled/tiled"
 	"github.com/matryer/is"
+	"image"
+	"image/color"
+	"io"
+	"io/fs"
+	"math"
+	"math/rand/v2"
+	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 	"unsafe"
 )
 
@@ -17,6 +36,8 @@ func TestMapLoading(t *testing.T) {
 		"../testdata/csv.tmx",
 		"../testdata/b64zlib.tmx",
 		"../testdata/b64zstd.tmx",
+		"../testdata/b64deflate.tmx",
+		"../testdata/b64lz4.tmx",
 		"../testdata/externaltileset.tmx",
 		"../testdata/objecttemplates.tmx",
 	}
@@ -87,6 +108,3002 @@ func TestMapLoading(t *testing.T) {
 
 }
 
+func TestLayerDataCompression(t *testing.T) {
+	is := is.New(t)
+
+	baseline, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing baseline Map
+	want := baseline.Groups.WithName("Group").TileLayers.WithName("Layer").TileDefs
+
+	for _, path := range []string{
+		"../testdata/b64zlib.tmx",
+		"../testdata/b64zstd.tmx",
+		"../testdata/b64deflate.tmx",
+		"../testdata/b64lz4.tmx",
+	} {
+		t.Run(fmt.Sprintf("should match csv gids for %s", filepath.Base(path)), func(t *testing.T) {
+			m, err := tiled.New(path)
+			is.NoErr(err) // Error parsing Map
+
+			got := m.Groups.WithName("Group").TileLayers.WithName("Layer").TileDefs
+			is.Equal(len(got), len(want)) // Tile def count should match the csv baseline
+			for i := range want {
+				is.Equal(got[i].GlobalID, want[i].GlobalID) // Global IDs should match the csv baseline
+			}
+		})
+	}
+
+	t.Run("should reject unknown compression", func(t *testing.T) {
+		var l tiled.TileLayer
+		err := xml.Unmarshal([]byte(`<layer width="1" height="1"><data encoding="base64" compression="brotli">AQAAAA==</data></layer>`), &l)
+		is.True(errors.Is(err, tiled.ErrUnsupportedCompression)) // Error should be ErrUnsupportedCompression
+	})
+
+	t.Run("should reject truncated lz4", func(t *testing.T) {
+		var l tiled.TileLayer
+		err := xml.Unmarshal([]byte(`<layer width="1" height="1"><data encoding="base64" compression="lz4">BCJNGGRApw==</data></layer>`), &l)
+		is.True(errors.Is(err, tiled.ErrDecodingTileLayerData)) // Error should be ErrDecodingTileLayerData
+	})
+
+	// Frames written by the lz4 CLI; the single tile frames hold an uncompressed block with block and content checksums
+	linked := make([]tiled.GlobalID, 200*100)
+	for i := range linked {
+		linked[i] = tiled.GlobalID(i%9 + 1)
+	}
+	for _, tc := range []struct {
+		name          string
+		width, height int
+		data          string
+		want          []tiled.GlobalID
+		wantErr       string
+	}{
+		{"should decode an uncompressed block", 1, 1, "BCJNGHRAvQQAAIABAAAAk3a78wAAAACTdrvz", []tiled.GlobalID{1}, ""},
+		{"should decode linked blocks", 200, 100, "BCJNGERAXi8BAAD/FQEAAAACAAAAAwAAAAQAAAAFAAAABgAAAAcAAAAIAAAACQAAACQA/////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////8RQAAQAAABCAAAAD/D///////////////////////////////////////////////////////////////////////////+gUAACAAAAAAAAAHOr2uc=", linked, ""},
+		{"should skip a skippable frame", 1, 1, "XypNGAMAAABhYmMEIk0YdEC9BAAAgAEAAACTdrvzAAAAAJN2u/M=", []tiled.GlobalID{1}, ""},
+		{"should reject a bad magic", 1, 1, "BSJNGHRAvQQAAIABAAAAk3a78wAAAACTdrvz", nil, "unknown frame magic"},
+		{"should reject a header checksum mismatch", 1, 1, "BCJNGHRAQgQAAIABAAAAk3a78wAAAACTdrvz", nil, "checksum mismatch"},
+		{"should reject a block checksum mismatch", 1, 1, "BCJNGHRAvQQAAIABAAAAbHa78wAAAACTdrvz", nil, "checksum mismatch"},
+		{"should reject a content checksum mismatch", 1, 1, "BCJNGHRAvQQAAIABAAAAk3a78wAAAACTdrsM", nil, "checksum mismatch"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			is := is.New(t)
+
+			var l tiled.TileLayer
+			err := xml.Unmarshal([]byte(fmt.Sprintf(`<layer width="%d" height="%d"><data encoding="base64" compression="lz4">%s</data></layer>`,
+				tc.width, tc.height, tc.data)), &l)
+			if tc.wantErr != "" {
+				is.True(errors.Is(err, tiled.ErrDecodingTileLayerData)) // Error should be ErrDecodingTileLayerData
+				is.True(strings.Contains(err.Error(), tc.wantErr))      // Error should name the lz4 failure
+				return
+			}
+			is.NoErr(err)                    // Error decoding lz4 layer data
+			is.Equal(l.GlobalIDs(), tc.want) // GlobalIDs should match the uncompressed data
+		})
+	}
+}
+
+func TestWalkLayers(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	var visited []string
+	err = m.WalkLayers(func(layer any, path []string) error {
+		var name string
+		switch l := layer.(type) {
+		case *tiled.TileLayer:
+			name = l.Name
+		case *tiled.ObjectLayer:
+			name = l.Name
+		case *tiled.ImageLayer:
+			name = l.Name
+		case *tiled.Group:
+			name = l.Name
+		}
+		visited = append(visited, strings.Join(append(path, name), "/"))
+		return nil
+	})
+	is.NoErr(err)                                                                 // Error walking layers
+	is.Equal(visited, []string{"Group", "Group/Image", "Group/Layer", "Objects"}) // Layers should be visited in document order
+
+	stop := errors.New("stop")
+	count := 0
+	err = m.WalkLayers(func(layer any, path []string) error {
+		count++
+		return stop
+	})
+	is.True(errors.Is(err, stop)) // Walk should return the callback error
+	is.Equal(count, 1)            // Walk should stop at the first error
+}
+
+func TestLayerDocumentOrder(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/mixedgroup.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	names := func(layers []tiled.Layer) (n []string) {
+		for _, l := range layers {
+			n = append(n, l.LayerName())
+		}
+		return
+	}
+
+	is.Equal(names(m.Layers), []string{"Ground", "Outer", "Top"}) // Map layers should be in document order
+
+	outer := m.Groups.WithName("Outer")
+	is.Equal(names(outer.Layers), []string{"Markers", "Detail", "Inner", "Sky"}) // Group layers should be in document order
+	is.Equal(outer.Layers[1].LayerOpacity(), float32(.5))                        // Layer opacity should be exposed
+	_, ok := outer.Layers[0].(*tiled.ObjectLayer)
+	is.True(ok) // First Group layer should be an ObjectLayer
+
+	inner := outer.Groups.WithName("Inner")
+	is.Equal(names(inner.Layers), []string{"Overlay", "Backdrop"}) // Nested Group layers should be in document order
+}
+
+func TestClassQueries(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/classes.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	names := func(ol tiled.Objects) (n []string) {
+		for _, o := range ol {
+			n = append(n, o.Name)
+		}
+		return
+	}
+
+	terrain := m.TileLayers.WithClass("terrain")
+	is.Equal(len(terrain), 2)                  // Should have two tile layers with class `terrain`
+	is.Equal(terrain[0].Name, "Ground")        // First `terrain` layer should be `Ground`
+	is.Equal(terrain[1].Name, "Decor")         // Second `terrain` layer should be `Decor`
+	is.Equal(m.TileLayers.WithClass("x"), nil) // Unknown class should return nil
+
+	actors := m.ObjectLayers.WithName("Actors")
+	is.Equal(names(actors.Objects.WithClass("spawn")), []string{"hero", "goblin"}) // Layer should have two `spawn` objects
+
+	is.Equal(names(m.ObjectsWithClass("spawn")), []string{"hero", "goblin", "bat", "legacy"}) // Map should have four `spawn` objects, one by legacy type
+	is.Equal(names(m.ObjectsWithClass("pickup")), []string{"chest"})                          // Map should find nested `pickup` object
+	is.Equal(m.ObjectsWithClass("missing"), nil)                                              // Unknown class should return nil
+}
+
+func TestObjectByID(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	id, err := m.Properties.WithName("obj").ObjectID()
+	is.NoErr(err)                   // Property named `obj` should be an object reference
+	is.Equal(id, tiled.ObjectID(3)) // Property named `obj` should reference object `3`
+
+	o := m.ObjectByID(id)
+	is.True(o != nil)                                                   // Referenced object should be found
+	is.Equal(o.Name, "polyline")                                        // Referenced object should be `polyline`
+	is.True(m.ObjectLayers.WithName("Objects").Objects.WithID(id) == o) // Layer lookup should return the same Object
+
+	_, err = m.Properties.WithName("pi").ObjectID()
+	is.True(errors.Is(err, tiled.ErrPropertyWrongType)) // Non object Property should fail
+
+	is.True(m.ObjectByID(99) == nil) // Unknown ObjectID should return nil
+
+	m, err = tiled.New("../testdata/classes.tmx")
+	is.NoErr(err)                           // Error parsing Map
+	is.Equal(m.ObjectByID(5).Name, "chest") // Object nested in a Group should be found
+}
+
+func TestTilesetQueries(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/externaltileset.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	ts := m.Tilesets.WithSource("tileset.tsx")
+	is.True(ts != nil)                                    // Should find Tileset by source
+	is.Equal(ts.Name, "base")                             // Tileset loaded from `tileset.tsx` should be `base`
+	is.True(m.Tilesets.WithSource("./tileset.tsx") == ts) // Uncleaned source path should match
+	is.True(m.Tilesets.WithSource("other.tsx") == nil)    // Unknown source should return nil
+
+	m, err = tiled.New("../testdata/csv.tmx")
+	is.NoErr(err)                                      // Error parsing Map
+	is.Equal(len(m.Tilesets.WithClass("ts_class")), 1) // Should find Tileset by class
+	is.Equal(m.Tilesets.WithClass("missing"), nil)     // Unknown class should return nil
+	is.True(m.Tilesets.WithSource("") == nil)          // Embedded Tilesets should not match an empty source
+}
+
+func TestGlobalIDFlips(t *testing.T) {
+	is := is.New(t)
+
+	for _, h := range []bool{false, true} {
+		for _, v := range []bool{false, true} {
+			for _, d := range []bool{false, true} {
+				t.Run(fmt.Sprintf("should round-trip h=%t v=%t d=%t", h, v, d), func(t *testing.T) {
+					g := tiled.GlobalID(42).WithFlips(true, true, true).WithFlips(h, v, d)
+					is.Equal(g.IsFlippedHorizontally(), h)       // Horizontal flip should round-trip
+					is.Equal(g.IsFlippedVertically(), v)         // Vertical flip should round-trip
+					is.Equal(g.IsFlippedDiagonally(), d)         // Diagonal flip should round-trip
+					is.Equal(g.BareID(), uint32(42))             // Bare ID should be untouched
+					is.Equal(g.ClearFlips(), tiled.GlobalID(42)) // Clearing flips should restore the bare ID
+				})
+			}
+		}
+	}
+}
+
+func TestTileDefOrientation(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		h, v, d  bool
+		rotation int
+		mirrored bool
+	}{
+		{false, false, false, 0, false},
+		{true, false, false, 0, true},
+		{false, true, false, 180, true},
+		{true, true, false, 180, false},
+		{false, false, true, 270, true},
+		{true, false, true, 90, false},
+		{false, true, true, 270, false},
+		{true, true, true, 90, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("should orient h=%t v=%t d=%t", tt.h, tt.v, tt.d), func(t *testing.T) {
+			td := &tiled.TileDef{HorizontallyFlipped: tt.h, VerticallyFlipped: tt.v, DiagonallyFlipped: tt.d}
+			rotation, mirrored := td.Orientation()
+			is.Equal(rotation, tt.rotation) // Rotation should match the flip table
+			is.Equal(mirrored, tt.mirrored) // Mirroring should match the flip table
+		})
+	}
+}
+
+func TestTileLayerNeighbors(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+	tl := m.Groups.WithName("Group").TileLayers.WithName("Layer")
+
+	at := func(row, col int) *tiled.TileDef {
+		td, _ := tl.GetTileDefAtPosition(row, col)
+		return td
+	}
+
+	t.Run("should return all neighbors for an interior cell", func(t *testing.T) {
+		is.Equal(tl.Neighbors4(5, 5), [4]*tiled.TileDef{at(4, 5), at(5, 6), at(6, 5), at(5, 4)})
+		is.Equal(tl.Neighbors8(5, 5), [8]*tiled.TileDef{
+			at(4, 5), at(4, 6), at(5, 6), at(6, 6), at(6, 5), at(6, 4), at(5, 4), at(4, 4),
+		})
+	})
+
+	t.Run("should return nil neighbors off the edge", func(t *testing.T) {
+		is.Equal(tl.Neighbors4(0, 5), [4]*tiled.TileDef{nil, at(0, 6), at(1, 5), at(0, 4)})
+		is.Equal(tl.Neighbors4(5, tl.Width-1), [4]*tiled.TileDef{at(4, tl.Width-1), nil, at(6, tl.Width-1), at(5, tl.Width-2)})
+	})
+
+	t.Run("should return nil neighbors off the corner", func(t *testing.T) {
+		is.Equal(tl.Neighbors4(0, 0), [4]*tiled.TileDef{nil, at(0, 1), at(1, 0), nil})
+		is.Equal(tl.Neighbors8(tl.Height-1, tl.Width-1), [8]*tiled.TileDef{
+			at(tl.Height-2, tl.Width-1), nil, nil, nil, nil, nil, at(tl.Height-1, tl.Width-2), at(tl.Height-2, tl.Width-2),
+		})
+	})
+}
+
+func TestTileLayerGrid(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+	tl := m.Groups.WithName("Group").TileLayers.WithName("Layer")
+
+	grid := tl.Grid()
+	is.Equal(len(grid), tl.Height) // Grid should have a row per tile row
+	for r := range grid {
+		is.Equal(len(grid[r]), tl.Width) // Grid row should have a column per tile column
+		for c := range grid[r] {
+			td, err := tl.GetTileDefAtPosition(r, c)
+			is.NoErr(err)
+			is.True(grid[r][c] == td) // Grid cell should share the TileDef at the same position
+		}
+	}
+
+	empty := &tiled.TileLayer{Width: 2, Height: 2}
+	is.True(empty.Grid() == nil) // Layer without tile defs should have no grid
+}
+
+func TestObjectsInRect(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+	ol := m.ObjectLayers.WithName("Objects")
+
+	names := func(ol tiled.Objects) (n []string) {
+		for _, o := range ol {
+			n = append(n, o.Name)
+		}
+		return
+	}
+
+	rect := func(x1, y1, x2, y2 int) tiled.Rect {
+		return tiled.Rect{Min: tiled.Point{X: x1, Y: y1}, Max: tiled.Point{X: x2, Y: y2}}
+	}
+
+	is.Equal(names(ol.ObjectsInRect(rect(100, 700, 200, 800))), []string{"point"})  // Point inside the rect should be found
+	is.Equal(names(ol.ObjectsInRect(rect(0, 0, 100, 100))), []string{"text"})       // Partially overlapping text should be found
+	is.Equal(names(ol.ObjectsInRect(rect(50, 150, 100, 200))), []string{"square"})  // Rotated square bounds should be found
+	is.Equal(names(ol.ObjectsInRect(rect(440, 90, 460, 110))), []string{"polygon"}) // Polygon bounds should be found
+	is.Equal(names(ol.ObjectsInRect(rect(117, 711, 118, 712))), []string{"point"})  // Point on the rect min edge should be found
+	is.Equal(ol.ObjectsInRect(rect(100, 600, 117, 711)), nil)                       // Point on the rect max edge should not be found
+	is.Equal(ol.ObjectsInRect(rect(2000, 2000, 2100, 2100)), nil)                   // Rect outside every object should find nothing
+}
+
+func TestObjectLayerIndex(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+	ol := m.ObjectLayers.WithName("Objects")
+
+	rects := []tiled.Rect{
+		{Min: tiled.Point{X: 0, Y: 0}, Max: tiled.Point{X: 100, Y: 100}},
+		{Min: tiled.Point{X: 50, Y: 150}, Max: tiled.Point{X: 100, Y: 200}},
+		{Min: tiled.Point{X: 100, Y: 300}, Max: tiled.Point{X: 700, Y: 500}},
+		{Min: tiled.Point{X: 0, Y: 0}, Max: tiled.Point{X: 1000, Y: 1000}},
+		{Min: tiled.Point{X: 2000, Y: 2000}, Max: tiled.Point{X: 2100, Y: 2100}},
+	}
+
+	linear := make([]tiled.Objects, len(rects))
+	for i, r := range rects {
+		linear[i] = ol.QueryRect(r)
+		is.Equal(linear[i], ol.ObjectsInRect(r)) // Query without an index should scan linearly
+	}
+	point := ol.QueryPoint(700, 400)
+
+	ol.BuildIndex(64)
+	for i, r := range rects {
+		is.Equal(ol.QueryRect(r), linear[i]) // Indexed query should match the linear scan
+	}
+	is.Equal(ol.QueryPoint(700, 400), point)             // Indexed point query should match the linear scan
+	is.Equal(ol.QueryPoint(700, 400)[0].Name, "ellipse") // Point query should find the ellipse
+	is.Equal(ol.QueryPoint(-10, -10), nil)               // Point query outside every object should find nothing
+}
+
+func BenchmarkObjectLayerQuery(b *testing.B) {
+	objects := make(tiled.Objects, 10000)
+	for i := range objects {
+		objects[i] = &tiled.Object{
+			ObjectID: tiled.ObjectID(i),
+			X:        float32(i%100) * 40,
+			Y:        float32(i/100) * 40,
+			Width:    32,
+			Height:   32,
+		}
+	}
+	ol := &tiled.ObjectLayer{Objects: &objects}
+	r := tiled.Rect{Min: tiled.Point{X: 1000, Y: 1000}, Max: tiled.Point{X: 1640, Y: 1480}}
+
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ol.QueryRect(r)
+		}
+	})
+
+	ol.BuildIndex(128)
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ol.QueryRect(r)
+		}
+	})
+}
+
+func TestRect(t *testing.T) {
+	is := is.New(t)
+
+	rect := func(x1, y1, x2, y2 int) tiled.Rect {
+		return tiled.Rect{Min: tiled.Point{X: x1, Y: y1}, Max: tiled.Point{X: x2, Y: y2}}
+	}
+
+	r := rect(0, 0, 32, 16)
+	is.Equal(r.Width(), 32)                                           // Width should be 32
+	is.Equal(r.Height(), 16)                                          // Height should be 16
+	is.Equal(r.Size(), tiled.Point{X: 32, Y: 16})                     // Size should be 32x16
+	is.True(r.Intersects(rect(16, 8, 48, 24)))                        // Overlapping rects should intersect
+	is.True(r.Intersects(rect(4, 4, 8, 8)))                           // Contained rect should intersect
+	is.True(!r.Intersects(rect(32, 0, 64, 16)))                       // Rects touching on an edge should not intersect
+	is.True(!r.Intersects(rect(100, 100, 132, 116)))                  // Disjoint rects should not intersect
+	is.True(r.ContainsPoint(tiled.Point{X: 0, Y: 0}))                 // Min should be contained
+	is.True(!r.ContainsPoint(tiled.Point{X: 32, Y: 8}))               // Max edge should not be contained
+	is.Equal(r.Union(rect(16, 8, 48, 24)), rect(0, 0, 48, 24))        // Union of overlapping rects
+	is.Equal(r.Union(rect(100, 100, 132, 116)), rect(0, 0, 132, 116)) // Union of disjoint rects
+}
+
+func TestPoint(t *testing.T) {
+	is := is.New(t)
+
+	p := tiled.Point{X: 3, Y: -4}
+	is.Equal(p.Add(tiled.Point{X: 1, Y: 2}), tiled.Point{X: 4, Y: -2}) // Add should translate
+	is.Equal(p.Sub(tiled.Point{X: 1, Y: 2}), tiled.Point{X: 2, Y: -6}) // Sub should translate
+	is.Equal(p.Scale(-2), tiled.Point{X: -6, Y: 8})                    // Scale should multiply
+	is.True(p.Equals(tiled.Point{X: 3, Y: -4}))                        // Equal points should be equal
+	is.True(!p.Equals(tiled.Point{X: -4, Y: 3}))                       // Different points should not be equal
+}
+
+func TestPolyPoints(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/polygons.tmx")
+	is.NoErr(err) // Error parsing Map
+	objects := m.ObjectLayers.WithName("Shapes").Objects
+
+	poly := objects.WithName("fractional").Polygon
+	fpts, err := poly.PointsF()
+	is.NoErr(err) // Fractional polygon points should parse
+	is.Equal(fpts, []tiled.PointF{{X: 0, Y: 0}, {X: 10.5, Y: -3.25}, {X: -7.75, Y: 12}})
+	pts, err := poly.Points()
+	is.NoErr(err) // Fractional polygon points should parse as integers
+	is.Equal(pts, []tiled.Point{{X: 0, Y: 0}, {X: 10, Y: -3}, {X: -7, Y: 12}})
+
+	fpts, err = objects.WithName("path").Polyline.PointsF()
+	is.NoErr(err) // Fractional polyline points should parse
+	is.Equal(fpts[1], tiled.PointF{X: 2.5, Y: 2.5})
+}
+
+func TestPolyPointsWhitespace(t *testing.T) {
+	is := is.New(t)
+
+	t.Run("should parse tab separated points", func(t *testing.T) {
+		pts, err := (&tiled.Poly{RawPoints: "0,0\t10,-5\t-3,4"}).Points()
+		is.NoErr(err)
+		is.Equal(pts, []tiled.Point{{X: 0, Y: 0}, {X: 10, Y: -5}, {X: -3, Y: 4}})
+	})
+
+	t.Run("should parse points with leading, trailing and repeated whitespace", func(t *testing.T) {
+		pts, err := (&tiled.Poly{RawPoints: "  -1,-2 \n\n  3,-4   "}).Points()
+		is.NoErr(err)
+		is.Equal(pts, []tiled.Point{{X: -1, Y: -2}, {X: 3, Y: -4}})
+	})
+
+	t.Run("should reject a malformed token", func(t *testing.T) {
+		_, err := (&tiled.Poly{RawPoints: "0,0 1-2,3 4,5"}).Points()
+		is.True(errors.Is(err, tiled.ErrDecodingPoly))  // Error should be ErrDecodingPoly
+		is.True(strings.Contains(err.Error(), "1-2,3")) // Error should identify the offending token
+	})
+
+	t.Run("should reject a token with missing coordinates", func(t *testing.T) {
+		_, err := (&tiled.Poly{RawPoints: "0,0 7"}).Points()
+		is.True(errors.Is(err, tiled.ErrDecodingPoly)) // Error should be ErrDecodingPoly
+	})
+}
+
+func TestKeepLayerData(t *testing.T) {
+	is := is.New(t)
+
+	layerData := func(path string, opts ...tiled.Option) []byte {
+		m, err := tiled.New(path, opts...)
+		is.NoErr(err) // Error parsing Map
+		return m.Groups.WithName("Group").TileLayers.WithName("Layer").LayerData
+	}
+
+	is.True(layerData("../testdata/csv.tmx") == nil) // Layer data should not be kept by default
+
+	csv := layerData("../testdata/csv.tmx", tiled.KeepLayerData())
+	is.Equal(len(csv), 28*18*4)                          // Layer data should hold a uint32 per tile
+	is.Equal(binary.LittleEndian.Uint32(csv), uint32(5)) // First tile GlobalID should be `5`
+	for _, path := range []string{"../testdata/b64zlib.tmx", "../testdata/b64zstd.tmx", "../testdata/b64deflate.tmx", "../testdata/b64lz4.tmx"} {
+		is.Equal(layerData(path, tiled.KeepLayerData()), csv) // Layer data should be identical across encodings
+	}
+
+	// Options end with the load they were given to, so a plain Unmarshal afterwards uses none
+	_, err := tiled.NewHeader("../testdata/csv.tmx", tiled.KeepLayerData())
+	is.NoErr(err) // Error parsing Map header
+	buf, err := os.ReadFile("../testdata/csv.tmx")
+	is.NoErr(err) // Error reading Map
+	var m tiled.Map
+	is.NoErr(xml.Unmarshal(buf, &m)) // Error unmarshalling Map
+	layer := m.Groups.WithName("Group").TileLayers.WithName("Layer")
+	is.Equal(len(layer.TileDefs), 28*18) // Layer data should be decoded after a header-only load
+	is.True(layer.LayerData == nil)      // Layer data should not be kept after a KeepLayerData load
+}
+
+func TestMapClone(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	c := m.Clone()
+	tl := m.Groups.WithName("Group").TileLayers.WithName("Layer")
+	ctl := c.Groups.WithName("Group").TileLayers.WithName("Layer")
+	ts := m.Tilesets.WithName("base")
+	cts := c.Tilesets.WithName("base")
+
+	is.True(cts != ts)                                                                                   // Clone should copy Tilesets
+	is.True(ctl.TileDefs[0].TileSet == cts)                                                              // Cloned TileDefs should reference the cloned Tileset
+	is.True(ctl.TileDefs[0].Tile == nil || ctl.TileDefs[0].Tile == cts.Tiles.WithID(ctl.TileDefs[0].ID)) // Cloned TileDefs should reference cloned Tiles
+	is.True(ctl.TileDefs[1].TileSet == ctl.TileDefs[2].TileSet)                                          // Cloned TileDefs should share the cloned Tileset
+	is.Equal(len(c.Layers), len(m.Layers))                                                               // Clone should keep the ordered layers
+	is.True(c.Layers[0] == c.Groups.WithName("Group"))                                                   // Cloned ordered layers should reference cloned layers
+
+	ctl.TileDefs[0].GlobalID = 9
+	cts.Name = "changed"
+	cts.Tiles.WithID(6).Properties = nil
+	c.Properties.WithName("xml").Value = "changed"
+	co := c.ObjectLayers.WithName("Objects").Objects.WithName("square")
+	co.X = 1
+	co.Name = "moved"
+	*c.ObjectLayers.WithName("Objects").Objects = append(*c.ObjectLayers.WithName("Objects").Objects, &tiled.Object{Name: "new"})
+
+	is.Equal(tl.TileDefs[0].GlobalID, tiled.GlobalID(5))                                    // Original tile should be unchanged
+	is.Equal(ts.Name, "base")                                                               // Original Tileset should be unchanged
+	is.Equal(m.Properties.WithName("xml").Value, "libxml2")                                 // Original Property should be unchanged
+	is.Equal(m.ObjectLayers.WithName("Objects").Objects.WithName("square").X, float32(128)) // Original Object should be unchanged
+	is.Equal(len(*m.ObjectLayers.WithName("Objects").Objects), 6)                           // Original Objects should be unchanged
+
+	collision := (*cts.Tiles.WithID(0).ObjectLayer.Objects)[0]
+	collision.Width = 1
+	is.Equal((*ts.Tiles.WithID(0).ObjectLayer.Objects)[0].Width, float32(10.25)) // Original collision Object should be unchanged
+	is.True(cts.Tiles.WithID(0).ObjectLayer != ts.Tiles.WithID(0).ObjectLayer)   // Clone should copy Tile collision layers
+
+	p, err := tiled.LoadProject("../testdata/proj.tiled-project")
+	is.NoErr(err) // Error parsing Project
+	m, err = tiled.New("../testdata/objecttemplates.tmx", tiled.WithProject(p))
+	is.NoErr(err) // Error parsing Map
+
+	c = m.Clone()
+	is.True(c.Project == p)        // Clone should share the Project
+	is.Equal(len(c.Validate()), 0) // Cloned template Objects should validate against the cloned Tilesets
+	co = c.ObjectLayers.WithName("Objects").Objects.WithID(14)
+	co.Properties.WithName("what").Value = "changed"
+	co.GlobalID = 9
+	(*c.Tilesets)[0].FirstGlobalID += 10
+	o := m.ObjectLayers.WithName("Objects").Objects.WithID(14)
+	is.Equal(o.Properties.WithName("what").Value, "point") // Original template Object Properties should be unchanged
+	is.Equal(o.GlobalID, tiled.GlobalID(0))                // Original template Object should be unchanged
+	errs := c.Validate()
+	is.Equal(len(errs), 2)                                     // Moving a cloned Tileset should affect both cloned tile template Objects
+	is.True(errors.Is(errs[0], tiled.ErrTemplateTilesetMoved)) // Error should be ErrTemplateTilesetMoved
+	is.Equal(len(m.Validate()), 0)                             // Moving a cloned Tileset should not affect the original
+}
+
+func TestNewMap(t *testing.T) {
+	is := is.New(t)
+
+	m := tiled.NewMap(4, 4, 16, 16)
+	is.Equal(m.Orientation, tiled.Orthogonal) // New Map should be orthogonal
+	is.Equal(m.NextLayerID, tiled.LayerID(1)) // New Map should start layer IDs at `1`
+
+	ground := m.AddTileLayer("Ground")
+	detail := m.AddTileLayer("Detail")
+	is.Equal(ground.ID, tiled.LayerID(1))               // First layer should have ID `1`
+	is.Equal(detail.ID, tiled.LayerID(2))               // Second layer should have ID `2`
+	is.Equal(m.NextLayerID, tiled.LayerID(3))           // Next layer ID should advance
+	is.Equal(ground.Opacity, float32(1))                // New layer should be opaque
+	is.True(ground.Visible)                             // New layer should be visible
+	is.Equal(len(ground.TileDefs), 16)                  // New layer should cover the Map
+	is.True(m.TileLayers.WithName("Detail") == detail)  // New layer should be found by name
+	is.Equal(m.Layers, []tiled.Layer{ground, detail})   // New layers should be in order
+	is.Equal(m.Clone().Layers[1].LayerName(), "Detail") // Cloned layers should keep their order
+
+	ts := &tiled.Tileset{FirstGlobalID: 1, Name: "base", TileWidth: 16, TileHeight: 16, TileCount: 9, Columns: 3}
+	*m.Tilesets = append(*m.Tilesets, ts)
+	ground.TileDefs[5] = &tiled.TileDef{ID: 2, GlobalID: 3, TileSet: ts}
+
+	td, err := ground.GetTileDefAtPosition(1, 1)
+	is.NoErr(err)
+	is.Equal(td.GlobalID, tiled.GlobalID(3)) // Set tile should be read back
+	td, err = ground.GetTileDefAtPosition(3, 3)
+	is.NoErr(err)
+	is.True(td.Nil) // Unset tile should be empty
+}
+
+func TestTileLayerSetTile(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+	tl := m.Groups.WithName("Group").TileLayers.WithName("Layer")
+
+	gid := tiled.GlobalID(7).WithFlips(true, false, false)
+	is.NoErr(tl.SetTile(2, 3, gid, m)) // Setting an in bounds tile should succeed
+
+	td, err := tl.GetTileDefAtPosition(2, 3)
+	is.NoErr(err)
+	is.Equal(td.GlobalID, gid)                  // Set tile should keep its GlobalID
+	is.Equal(td.ID, tiled.TileID(6))            // Set tile should resolve its TileID
+	is.True(td.TileSet == m.TilesetForGID(gid)) // Set tile should resolve its Tileset
+	is.True(td.Tile.HasAnimation())             // Set tile should resolve its Tile
+	is.True(td.HorizontallyFlipped)             // Set tile should keep its flips
+
+	is.NoErr(tl.SetTile(2, 3, 0, m)) // Clearing a tile should succeed
+	td, _ = tl.GetTileDefAtPosition(2, 3)
+	is.True(td.Nil) // Cleared tile should be empty
+
+	err = tl.SetTile(tl.Height, 0, 1, m)
+	is.True(errors.Is(err, tiled.ErrTileDefOutOfBounds)) // Out of bounds row should fail
+	err = tl.SetTile(0, -1, 1, m)
+	is.True(errors.Is(err, tiled.ErrTileDefOutOfBounds)) // Out of bounds column should fail
+
+	empty := tiled.NewMap(2, 2, 16, 16)
+	err = empty.AddTileLayer("Layer").SetTile(0, 0, 1, empty)
+	is.True(errors.Is(err, tiled.ErrNoSuitableTileset)) // Tile without a Tileset should fail
+}
+
+func TestTileDefSourceRect(t *testing.T) {
+	is := is.New(t)
+
+	rect := func(x1, y1, x2, y2 int) *tiled.Rect {
+		return &tiled.Rect{Min: tiled.Point{X: x1, Y: y1}, Max: tiled.Point{X: x2, Y: y2}}
+	}
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+	tl := m.Groups.WithName("Group").TileLayers.WithName("Layer")
+
+	for _, tt := range []struct {
+		gid  tiled.GlobalID
+		want *tiled.Rect
+	}{
+		{1, rect(0, 0, 32, 32)},
+		{3, rect(66, 0, 98, 32)},
+		{5, rect(33, 33, 65, 65)},
+		{9, rect(66, 66, 98, 98)},
+		{tiled.GlobalID(5).WithFlips(true, true, true), rect(33, 33, 65, 65)},
+	} {
+		is.NoErr(tl.SetTile(0, 0, tt.gid, m))
+		td, _ := tl.GetTileDefAtPosition(0, 0)
+		r, err := td.SourceRect()
+		is.NoErr(err)        // Atlas tile should have a source rect
+		is.Equal(r, tt.want) // Source rect should account for spacing
+	}
+
+	_, err = (&tiled.TileDef{Nil: true}).SourceRect()
+	is.True(errors.Is(err, tiled.ErrNoTileImage)) // Empty tile should have no source rect
+
+	tiles := tiled.Tiles{
+		{TileID: 0, Image: &tiled.Image{Source: "a.png", Width: 64, Height: 48}},
+		{TileID: 1, X: 8, Y: 4, Width: 16, Height: 16, Image: &tiled.Image{Source: "b.png", Width: 64, Height: 48}},
+	}
+	collection := &tiled.Tileset{FirstGlobalID: 1, Tiles: &tiles}
+	r, err := (&tiled.TileDef{TileSet: collection, Tile: tiles[0]}).SourceRect()
+	is.NoErr(err)                   // Collection tile should have a source rect
+	is.Equal(r, rect(0, 0, 64, 48)) // Collection tile should cover its image
+	r, err = (&tiled.TileDef{ID: 1, TileSet: collection, Tile: tiles[1]}).SourceRect()
+	is.NoErr(err)                   // Collection sub-rect tile should have a source rect
+	is.Equal(r, rect(8, 4, 24, 20)) // Collection tile should honour its sub-rect
+}
+
+func TestTileDefCollisionObjects(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/externaltileset.tmx")
+	is.NoErr(err) // Error parsing Map
+	tl := m.Groups.WithName("Group").TileLayers.WithName("Layer")
+
+	td, err := tl.GetTileDefAtPosition(1, 8)
+	is.NoErr(err)
+	is.Equal(td.ID, tiled.TileID(0)) // Tile at row 1, col 8 should be tile `0`
+
+	objects := td.CollisionObjects()
+	is.Equal(len(objects), 1)                   // Tile `0` should have a collision rectangle
+	is.Equal(objects[0].X, float32(11.75))      // Collision rectangle should keep its position
+	is.Equal(objects[0].Height, float32(25.25)) // Collision rectangle should keep its size
+
+	td, _ = tl.GetTileDefAtPosition(0, 1)
+	is.Equal(td.CollisionObjects(), nil)                          // Tile without an object layer should have no collision objects
+	is.Equal((&tiled.TileDef{Nil: true}).CollisionObjects(), nil) // Empty tile should have no collision objects
+}
+
+func TestMapPixelSize(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+	w, h := m.PixelSize()
+	is.Equal([]int{w, h}, []int{896, 576}) // Orthogonal size should be tiles by tile size
+
+	m = tiled.NewMap(10, 6, 64, 32)
+	m.Orientation = tiled.Isometric
+	w, h = m.PixelSize()
+	is.Equal([]int{w, h}, []int{512, 256}) // Isometric size should bound the diamond
+
+	m = tiled.NewMap(4, 4, 64, 32)
+	m.Orientation = tiled.Staggered
+	m.StaggerAxis = "y"
+	w, h = m.PixelSize()
+	is.Equal([]int{w, h}, []int{288, 80}) // Staggered size should include the stagger offset
+
+	m = tiled.NewMap(4, 4, 14, 12)
+	m.Orientation = tiled.Hexagonal
+	m.StaggerAxis = "y"
+	m.HexSideLength = 6
+	w, h = m.PixelSize()
+	is.Equal([]int{w, h}, []int{63, 39}) // Hexagonal size should include the side length
+}
+
+func TestLenientEnums(t *testing.T) {
+	is := is.New(t)
+
+	t.Run("should fail on an unknown orientation by default", func(t *testing.T) {
+		_, err := tiled.New("../testdata/unknownorientation.tmx")
+		is.True(errors.Is(err, tiled.ErrUnknownOrientation)) // Error should be ErrUnknownOrientation
+	})
+
+	t.Run("should fail on an unknown orientation when strict", func(t *testing.T) {
+		_, err := tiled.New("../testdata/unknownorientation.tmx", tiled.LenientEnums(false))
+		is.True(errors.Is(err, tiled.ErrUnknownOrientation)) // Error should be ErrUnknownOrientation
+	})
+
+	t.Run("should default an unknown orientation when lenient", func(t *testing.T) {
+		m, err := tiled.New("../testdata/unknownorientation.tmx", tiled.LenientEnums(true))
+		is.NoErr(err)                                           // Lenient load should succeed
+		is.Equal(m.Orientation, tiled.Orthogonal)               // Unknown orientation should fall back to the default
+		is.Equal(len(m.Warnings()), 1)                          // Unknown orientation should be recorded as a warning
+		is.True(strings.Contains(m.Warnings()[0], "octagonal")) // Warning should name the unknown value
+	})
+
+	t.Run("should not carry warnings into the next load", func(t *testing.T) {
+		m, err := tiled.New("../testdata/mixedgroup.tmx", tiled.LenientEnums(true))
+		is.NoErr(err)
+		is.Equal(len(m.Warnings()), 0) // Map without unknown values should have no warnings
+	})
+
+	t.Run("should not share decode state with a concurrent xml.Unmarshal", func(t *testing.T) {
+		buf, err := os.ReadFile("../testdata/unknownorientation.tmx")
+		is.NoErr(err)
+
+		const n = 8
+		var (
+			wg                   sync.WaitGroup
+			lenientErrs, xmlErrs [n]error
+			warnings             [n]int
+		)
+		for i := range n {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				m, err := tiled.New("../testdata/unknownorientation.tmx", tiled.LenientEnums(true))
+				if lenientErrs[i] = err; err == nil {
+					warnings[i] = len(m.Warnings())
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				var m tiled.Map
+				xmlErrs[i] = xml.Unmarshal(buf, &m)
+			}()
+		}
+		wg.Wait()
+
+		for i := range n {
+			is.NoErr(lenientErrs[i])                                    // Lenient load should succeed
+			is.Equal(warnings[i], 1)                                    // Lenient load should keep only its own warning
+			is.True(errors.Is(xmlErrs[i], tiled.ErrUnknownOrientation)) // xml.Unmarshal should decode with the zero Options
+		}
+	})
+}
+
+func TestMapWarnings(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/terrain.tmx")
+	is.NoErr(err) // Deprecated terrain should not fail the load
+
+	has := func(substr string) bool {
+		for _, w := range m.Warnings() {
+			if strings.Contains(w, substr) {
+				return true
+			}
+		}
+		return false
+	}
+	is.True(has("deprecated terraintypes"))            // Deprecated terraintypes should be reported
+	is.True(has("tile 1 uses the deprecated terrain")) // Deprecated terrain attribute should be reported
+	is.True(has("frame without a duration"))           // Zero duration frame should be reported
+
+	m, err = tiled.New("../testdata/csv.tmx")
+	is.NoErr(err)
+	is.Equal(len(m.Warnings()), 0) // Clean map should have no warnings
+}
+
+func TestObjectEffectiveProperties(t *testing.T) {
+	is := is.New(t)
+
+	tiled.RegisterClass("spawn", &tiled.Properties{
+		{Name: "health", Type: tiled.Int, Value: "10"},
+		{Name: "hostile", Type: tiled.Bool, Value: "true"},
+	})
+	defer tiled.RegisterClass("spawn", nil)
+
+	m, err := tiled.New("../testdata/classes.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	hero := m.ObjectByID(1)
+	hero.Properties = &tiled.Properties{
+		{Name: "hostile", Type: tiled.Bool, Value: "false"},
+		{Name: "title", Value: "Hero"},
+	}
+
+	props := hero.EffectiveProperties()
+	is.Equal(len(*props), 3)                           // Defaults and own Properties should be merged
+	is.Equal(props.WithName("health").Value, "10")     // Default Property should be inherited
+	is.Equal(props.WithName("hostile").Value, "false") // Own Property should override the default
+	is.Equal(props.WithName("title").Value, "Hero")    // Own only Property should be kept
+	is.Equal(len(*hero.Properties), 2)                 // Own Properties should be unchanged
+
+	goblin := m.ObjectByID(3)
+	is.Equal(goblin.EffectiveProperties().WithName("hostile").Value, "true") // Object without Properties should inherit defaults
+
+	legacy := m.ObjectByID(6)
+	is.Equal(legacy.EffectiveProperties().WithName("health").Value, "10") // Object type should be used when it has no class
+
+	chest := m.ObjectByID(5)
+	is.True(chest.EffectiveProperties() == chest.Properties) // Unregistered class should return own Properties
+}
+
+func TestLoadProject(t *testing.T) {
+	is := is.New(t)
+
+	p, err := tiled.LoadProject("../testdata/proj.tiled-project")
+	is.NoErr(err) // Error parsing Project
+
+	is.Equal(len(p.Classes()), 1) // Project should declare one class
+	is.Equal(len(p.Enums()), 1)   // Project should declare one enum
+	enum := p.EnumWithName("MyEnum")
+	is.Equal(enum.Values, []string{"VAL1", "VAL2", "VAL3"}) // Enum should have its values
+	is.True(enum.ValuesAsFlags)                             // Enum should be a flag enum
+
+	defaults, err := p.ClassDefaults("MyClass")
+	is.NoErr(err)                                        // Class defaults should convert to Properties
+	is.Equal(defaults.WithName("MyInt").Type, tiled.Int) // Class member type should be kept
+	is.Equal(defaults.WithName("MyInt").Value, "0")      // Class member default should be read
+	is.Equal(defaults.WithName("MyName").Value, "")      // Class member default should be read
+
+	m, err := tiled.New("../testdata/classes.tmx", tiled.WithProject(p))
+	is.NoErr(err) // Error parsing Map
+
+	is.True(m.Project == p) // Map should keep its Project
+	props := m.ObjectLayers.WithName("Actors").Objects.WithName("custom").EffectiveProperties()
+	is.Equal(props.WithName("MyInt").Value, "5") // Own Property should override the class default
+	is.True(props.WithName("MyName") != nil)     // Class default should be inherited from the Project
+
+	m, err = tiled.New("../testdata/classes.tmx")
+	is.NoErr(err) // Error parsing Map
+	props = m.ObjectLayers.WithName("Actors").Objects.WithName("custom").EffectiveProperties()
+	is.True(props.WithName("MyName") == nil) // Project classes should not leak into later loads
+}
+
+func TestPropertyEnumValues(t *testing.T) {
+	is := is.New(t)
+
+	p, err := tiled.LoadProject("../testdata/proj.tiled-project")
+	is.NoErr(err) // Error parsing Project
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	prop := m.Properties.WithName("my_enum")
+	values, err := prop.EnumValues(p)
+	is.NoErr(err)                              // Flag enum Property should resolve
+	is.Equal(values, []string{"VAL1", "VAL3"}) // Flag enum `5` should hold `VAL1` and `VAL3`
+	_, err = prop.EnumValue(p)
+	is.True(errors.Is(err, tiled.ErrPropertyFailedConversion)) // Multiple flags should not be a single value
+
+	single := tiled.Property{Name: "one", Type: tiled.Int, CustomType: "MyEnum", Value: "2"}
+	v, err := single.EnumValue(p)
+	is.NoErr(err)       // Single flag should be a single value
+	is.Equal(v, "VAL2") // Flag `2` should be `VAL2`
+
+	bad := tiled.Property{Name: "bad", Type: tiled.Int, CustomType: "MyEnum", Value: "8"}
+	_, err = bad.EnumValues(p)
+	is.True(errors.Is(err, tiled.ErrPropertyFailedConversion)) // Undeclared flag should fail
+
+	p.EnumWithName("MyEnum").StorageType = "string"
+	str := tiled.Property{Name: "str", Type: tiled.String, CustomType: "MyEnum", Value: "VAL3,VAL1"}
+	values, err = str.EnumValues(p)
+	is.NoErr(err)                              // String flag enum Property should resolve
+	is.Equal(values, []string{"VAL3", "VAL1"}) // String flag enum should hold both values
+	str.Value = "VAL4"
+	_, err = str.EnumValues(p)
+	is.True(errors.Is(err, tiled.ErrPropertyFailedConversion)) // Undeclared string value should fail
+
+	_, err = m.Properties.WithName("pi").EnumValues(p)
+	is.True(errors.Is(err, tiled.ErrUnknownCustomType)) // Property without an enum should fail
+}
+
+func TestUsedTilesets(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/twotilesets.tmx")
+	is.NoErr(err)                 // Error parsing Map
+	is.Equal(len(*m.Tilesets), 3) // Map should declare three Tilesets
+
+	used := m.UsedTilesets()
+	is.Equal(len(used), 2)            // Map should use two Tilesets
+	is.Equal(used[0].Name, "base")    // Tileset used by a nested tile layer should be listed
+	is.Equal(used[1].Name, "objects") // Tileset used only by a tile Object should be listed
+
+	is.Equal(tiled.NewMap(1, 1, 1, 1).UsedTilesets(), nil) // Map without tiles should use no Tilesets
+}
+
+func TestTileHistogram(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+	h := m.Groups.WithName("Group").TileLayers.WithName("Layer").TileHistogram()
+	is.Equal(h[3], 307) // Tile `3` should appear 307 times
+	is.Equal(h[9], 67)  // Tile `9` should appear 67 times
+	is.Equal(h[2], 11)  // Tile `2` should appear 11 times
+	is.Equal(len(h), 9) // Layer should use nine distinct tiles
+
+	m, err = tiled.New("../testdata/mixedgroup.tmx")
+	is.NoErr(err) // Error parsing Map
+	h = m.TileHistogram()
+	is.Equal(len(h), 7) // Map should use seven distinct tiles across its layers
+	is.Equal(h[7], 1)   // Tile `7` from a nested group layer should be counted
+	is.Equal(h[0], 0)   // Empty tiles should not be counted
+}
+
+func TestTileLayerFloodRegion(t *testing.T) {
+	is := is.New(t)
+
+	m := tiled.NewMap(4, 4, 32, 32)
+	*m.Tilesets = append(*m.Tilesets, &tiled.Tileset{FirstGlobalID: 1, Name: "base", TileWidth: 32, TileHeight: 32})
+	tl := m.AddTileLayer("Layer")
+	for i, gid := range []tiled.GlobalID{
+		1, 1, 2, 1,
+		1, 2, 2, 1,
+		2, 2, 1, 1,
+		1, 2, 1, 2,
+	} {
+		is.NoErr(tl.SetTile(i/4, i%4, gid, m))
+	}
+
+	isOne := func(td *tiled.TileDef) bool {
+		return td.GlobalID == 1
+	}
+	point := func(x, y int) tiled.Point {
+		return tiled.Point{X: x, Y: y}
+	}
+
+	is.Equal(tl.FloodRegion(0, 0, isOne), []tiled.Point{point(0, 0), point(1, 0), point(0, 1)}) // Region should be 4-connected
+	is.Equal(len(tl.FloodRegion(0, 3, isOne)), 5)                                               // Region should follow the right column and bend
+	is.Equal(tl.FloodRegion(3, 0, isOne), []tiled.Point{point(0, 3)})                           // Diagonal cells should not be connected
+	is.Equal(tl.FloodRegion(0, 2, isOne), nil)                                                  // Non matching start should be empty
+	is.Equal(tl.FloodRegion(4, 0, isOne), nil)                                                  // Out of bounds start should be empty
+}
+
+func TestTileLayerRaycast(t *testing.T) {
+	is := is.New(t)
+
+	m := tiled.NewMap(5, 5, 32, 32)
+	*m.Tilesets = append(*m.Tilesets, &tiled.Tileset{FirstGlobalID: 1, Name: "base", TileWidth: 32, TileHeight: 32})
+	tl := m.AddTileLayer("Layer")
+	for row := 0; row < 4; row++ {
+		is.NoErr(tl.SetTile(row, 2, 2, m)) // Wall down column 2, open on the last row
+	}
+
+	isWall := func(td *tiled.TileDef) bool {
+		return td.GlobalID == 2
+	}
+	point := func(x, y int) tiled.Point {
+		return tiled.Point{X: x, Y: y}
+	}
+
+	hit, at := tl.Raycast(1, 0, 1, 4, isWall)
+	is.True(hit)              // Ray across the wall should hit
+	is.Equal(at, point(2, 1)) // Ray should stop at the first wall cell
+
+	hit, at = tl.Raycast(0, 0, 3, 4, isWall)
+	is.True(hit)      // Diagonal ray across the wall should hit
+	is.Equal(at.X, 2) // Diagonal ray should stop in the wall column
+
+	hit, at = tl.Raycast(4, 0, 4, 4, isWall)
+	is.True(!hit)             // Ray through the gap should not hit
+	is.Equal(at, point(4, 4)) // Unblocked ray should end at the target
+
+	hit, at = tl.Raycast(2, 2, 2, 2, isWall)
+	is.True(!hit)             // Ray within a single cell should not hit
+	is.Equal(at, point(2, 2)) // Single cell ray should end at the target
+
+	hit, at = tl.Raycast(1, -3, 1, 9, isWall)
+	is.True(hit)              // Ray with out of bounds ends should still hit
+	is.Equal(at, point(2, 1)) // Out of bounds ray should stop at the wall
+}
+
+func TestMapEditorSettings(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/infinite.tmx")
+	is.NoErr(err)                                                               // Error parsing Map
+	is.True(m.Infinite)                                                         // Map should be infinite
+	is.Equal(m.EditorSettings.ChunkSize, tiled.ChunkSize{Width: 32, Height: 8}) // Chunk size should be read
+	is.Equal(m.EditorSettings.Export.Target, "infinite.json")                   // Export target should be read
+	is.Equal(m.EditorSettings.Export.Format, "json")                            // Export format should be read
+
+	m, err = tiled.New("../testdata/csv.tmx")
+	is.NoErr(err)                                                                                                           // Error parsing Map
+	is.Equal(m.EditorSettings.ChunkSize, tiled.ChunkSize{Width: tiled.DefaultChunkWidth, Height: tiled.DefaultChunkHeight}) // Chunk size should default to 16x16
+	is.True(m.EditorSettings.Export == nil)                                                                                 // Export should be absent
+}
+
+func TestUnknownXML(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/unknown.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	const ext = "https://example.com/ext"
+	attr := func(attrs []xml.Attr, local string) string {
+		for _, a := range attrs {
+			if a.Name.Space == ext && a.Name.Local == local {
+				return a.Value
+			}
+		}
+		return ""
+	}
+
+	is.Equal(attr(m.UnknownAttrs, "author"), "someone")                                  // Unknown Map attribute should be kept
+	is.Equal(attr(m.TileLayers.WithName("Ground").UnknownAttrs, "layer"), "terrain")     // Unknown layer attribute should be kept
+	is.Equal(len(m.ObjectLayers.WithName("Objects").UnknownAttrs), 0)                    // Known layer attributes should not be kept as unknown
+	is.Equal(m.Properties.WithName("extended").UnknownElements[0].XMLName.Local, "hint") // Unknown Property element should be kept
+	o := m.ObjectByID(1)
+	is.Equal(attr(o.UnknownAttrs, "weight"), "3")       // Unknown Object attribute should be kept
+	is.Equal(o.UnknownElements[0].InnerXML, "return 1") // Unknown Object element should be kept
+
+	is.Equal(len(m.UnknownElements), 1) // Unknown Map element should be kept
+	meta := m.UnknownElements[0]
+	is.Equal(meta.XMLName, xml.Name{Space: ext, Local: "meta"}) // Unknown element name should be kept
+	is.Equal(meta.InnerXML, "<ext:note>generated</ext:note>")   // Unknown element content should be kept
+
+	out, err := xml.Marshal(meta)
+	is.NoErr(err) // Unknown element should be written back out
+	var back tiled.UnknownElement
+	is.NoErr(xml.Unmarshal(out, &back))
+	is.Equal(back.XMLName, meta.XMLName)                                                        // Unknown element name should survive a round-trip
+	is.Equal(back.InnerXML, meta.InnerXML)                                                      // Unknown element content should survive a round-trip
+	is.True(slices.Contains(back.Attrs, xml.Attr{Name: xml.Name{Local: "build"}, Value: "42"})) // Unknown element attributes should survive a round-trip
+}
+
+func TestMapLayerByID(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/mixedgroup.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	for _, tt := range []struct {
+		id   tiled.LayerID
+		name string
+	}{
+		{1, "Ground"},
+		{2, "Outer"},
+		{3, "Markers"},
+		{5, "Inner"},
+		{7, "Backdrop"},
+		{9, "Top"},
+	} {
+		l, ok := m.LayerByID(tt.id)
+		is.True(ok)                                    // Layer should be found by ID
+		is.Equal(l.(tiled.Layer).LayerName(), tt.name) // Layer found by ID should match its name
+	}
+
+	g, _ := m.LayerByID(2)
+	is.Equal(g.(*tiled.Group).ID, tiled.LayerID(2)) // Group ID should be typed
+	is.Equal(m.NextLayerID, tiled.LayerID(10))      // Next layer ID should be typed
+
+	l, ok := m.LayerByID(42)
+	is.True(!ok)      // Unknown ID should not be found
+	is.True(l == nil) // Unknown ID should return nil
+}
+
+func TestTilesetValidate(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/validate.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	good := m.Tilesets.WithName("good")
+	is.True(good != nil)              // Tileset `good` should exist
+	is.Equal(len(good.Validate()), 0) // Consistent Tileset should have no errors
+
+	resized := m.Tilesets.WithName("resized")
+	is.True(resized != nil) // Tileset `resized` should exist
+
+	errs := resized.Validate()
+	is.Equal(len(errs), 3)                                       // Resized Tileset should have 3 errors
+	is.True(errors.Is(errs[0], tiled.ErrImageSizeMismatch))      // Image width should not hold 4 columns
+	is.True(strings.Contains(errs[0].Error(), "image width 96")) // Error should report the image width
+	is.True(errors.Is(errs[1], tiled.ErrTileCountMismatch))      // Tile count should not match columns and rows
+	is.True(errors.Is(errs[2], tiled.ErrTileIDOutOfRange))       // Tile 9 should be out of range
+
+	uncounted := m.Tilesets.WithName("uncounted")
+	is.True(uncounted != nil)              // Tileset `uncounted` should exist
+	is.Equal(len(uncounted.Validate()), 0) // Tileset omitting its tile count should have no errors
+}
+
+func TestTilesetFrames(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	ts := (*m.Tilesets)[0]
+	anim := *ts.Tiles.WithID(6).Animation
+	is.Equal(len(anim), 7) // Tile 6 should have 7 Frames
+
+	is.Equal(ts.FrameGlobalID(anim[0]), ts.FirstGlobalID)   // First Frame should show the first tile
+	is.Equal(ts.FrameGlobalID(anim[4]), ts.FirstGlobalID+4) // Fifth Frame should show tile 4
+
+	r, err := ts.FrameRect(anim[4])
+	is.NoErr(err)                                                                            // Error getting Frame rect
+	is.Equal(*r, tiled.Rect{Min: tiled.Point{X: 33, Y: 33}, Max: tiled.Point{X: 65, Y: 65}}) // Tile 4 rect should account for spacing
+
+	r, err = ts.FrameRect(anim[6])
+	is.NoErr(err)                                                                           // Error getting Frame rect
+	is.Equal(*r, tiled.Rect{Min: tiled.Point{X: 0, Y: 66}, Max: tiled.Point{X: 32, Y: 98}}) // Tile 6 rect should be on the third row
+}
+
+func TestObjectLayerOrderedObjects(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/draworder.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	names := func(ol tiled.Objects) []string {
+		var n []string
+		for _, o := range ol {
+			n = append(n, o.Name)
+		}
+		return n
+	}
+
+	topDown := m.ObjectLayers.WithName("TopDown")
+	is.Equal(topDown.DrawOrder, tiled.TopDown)                                          // Layer should default to TopDown
+	is.Equal(names(topDown.OrderedObjects()), []string{"rock", "sign", "tree", "bush"}) // TopDown should sort by Y, keeping ties in order
+	is.Equal(names(*topDown.Objects), []string{"tree", "rock", "bush", "sign"})         // Objects should keep document order
+
+	index := m.ObjectLayers.WithName("Index")
+	is.Equal(index.DrawOrder, tiled.Index)                                            // Layer should use Index
+	is.Equal(names(index.OrderedObjects()), []string{"tree", "rock", "bush", "sign"}) // Index should keep document order
+}
+
+func TestMapEffectiveTint(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/cascade.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+	is.Equal(m.EffectiveTint([]string{"Outer"}), color.RGBA{R: 255, G: 128, B: 64, A: 255})                    // Group tint should apply
+	is.Equal(m.EffectiveTint([]string{"Outer", "Markers"}), color.RGBA{R: 255, G: 128, B: 64, A: 255})         // Untinted layer should inherit Group tint
+	is.Equal(m.EffectiveTint([]string{"Outer", "Inner", "Tinted"}), color.RGBA{R: 255, G: 128, B: 32, A: 128}) // Layer tint should multiply Group tint
+	is.Equal(m.EffectiveTint([]string{"Top"}), white)                                                          // Untinted layer should be white
+	is.Equal(m.EffectiveTint([]string{"Outer", "Missing"}), white)                                             // Unknown path should be white
+}
+
+func TestMapEffectiveOpacity(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/cascade.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	outer := m.Groups.WithName("Outer")
+	inner := outer.Groups.WithName("Inner")
+	tinted := inner.TileLayers.WithName("Tinted")
+	markers := outer.ObjectLayers.WithName("Markers")
+	top := m.TileLayers.WithName("Top")
+
+	is.Equal(m.EffectiveOpacity(outer), float32(.5))    // Top level Group should keep its opacity
+	is.Equal(m.EffectiveOpacity(inner), float32(.25))   // Nested Group should multiply its parent opacity
+	is.Equal(m.EffectiveOpacity(tinted), float32(.125)) // Layer should multiply every ancestor opacity
+	is.Equal(m.EffectiveOpacity(markers), float32(.5))  // Layer without opacity should default to opaque
+	is.Equal(m.EffectiveOpacity(top), float32(1))       // Top level layer without opacity should be opaque
+}
+
+func TestObjectKind(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	objects := m.ObjectLayers.WithName("Objects").Objects
+	for _, tt := range []struct {
+		name string
+		kind tiled.ObjectKind
+	}{
+		{"square", tiled.KindRect},
+		{"polygon", tiled.KindPolygon},
+		{"polyline", tiled.KindPolyline},
+		{"ellipse", tiled.KindEllipse},
+		{"text", tiled.KindText},
+		{"point", tiled.KindPoint},
+	} {
+		is.Equal(objects.WithName(tt.name).Kind(), tt.kind) // Object kind should match its shape
+	}
+
+	m, err = tiled.New("../testdata/twotilesets.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	crate := m.ObjectLayers.WithName("Things").Objects.WithName("crate")
+	is.Equal(crate.Kind(), tiled.KindTile) // Object with a gid should be a tile
+}
+
+func TestObjectText(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/text.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	labels := m.ObjectLayers.WithName("Labels").Objects
+
+	warning := labels.WithName("warning").Text
+	is.Equal(warning.FontFamily, "Serif")   // Text font family should be `Serif`
+	is.Equal(warning.PixelSize, 24)         // Text pixel size should be 24
+	is.True(warning.Wrap)                   // Text should wrap
+	is.True(!warning.Kerning)               // Text kerning should be disabled
+	is.Equal(warning.HAlign, tiled.HCenter) // Text should be centered horizontally
+	is.Equal(warning.VAlign, tiled.VBottom) // Text should be aligned to the bottom
+	is.Equal(warning.Color, "#80ff0000")    // Text color should be `#80ff0000`
+
+	c, err := warning.ColorRGBA()
+	is.NoErr(err)                                       // Error parsing Text color
+	is.Equal(c, color.RGBA{R: 255, G: 0, B: 0, A: 128}) // Text color should be half transparent red
+
+	plain := labels.WithName("plain").Text
+	is.Equal(plain.Value, "Hello")           // Text value should be `Hello`
+	is.Equal(plain.FontFamily, "sans-serif") // Text font family should default to `sans-serif`
+	is.Equal(plain.PixelSize, 16)            // Text pixel size should default to 16
+	is.True(plain.Kerning)                   // Text kerning should default to enabled
+	is.Equal(plain.HAlign, tiled.HLeft)      // Text should default to left alignment
+	is.Equal(plain.VAlign, tiled.VTop)       // Text should default to top alignment
+	is.True(!plain.Wrap)                     // Text should default to not wrapping
+
+	c, err = plain.ColorRGBA()
+	is.NoErr(err)                   // Error parsing Text color
+	is.Equal(c, color.RGBA{A: 255}) // Text color should default to black
+}
+
+func TestMapWriteJSON(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/b64zlib.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	var buf bytes.Buffer
+	is.NoErr(m.WriteJSON(&buf)) // Error writing JSON
+
+	var jm struct {
+		Type        string
+		Orientation tiled.Orientation
+		RenderOrder tiled.RenderOrder
+		Width       int
+		Height      int
+		Properties  []struct {
+			Name  string
+			Type  tiled.PropertyType
+			Value any
+		}
+		Tilesets []struct {
+			FirstGID tiled.GlobalID `json:"firstgid"`
+			Name     string
+			Tiles    []struct {
+				ID        tiled.TileID
+				Animation []struct{ TileID, Duration int }
+			}
+		}
+		Layers []struct {
+			Type        string
+			Name        string
+			Encoding    string
+			Compression string
+			Data        any
+			DrawOrder   tiled.DrawOrder
+			Objects     []struct {
+				Name    string
+				Type    string
+				Polygon []tiled.PointF
+				Ellipse bool
+				Text    *struct {
+					Text   string
+					Color  string
+					HAlign tiled.HAlignment
+				}
+			}
+			Layers []struct {
+				Type        string
+				Encoding    string
+				Compression string
+				Data        string
+			}
+		}
+	}
+	is.NoErr(json.Unmarshal(buf.Bytes(), &jm)) // Error reading written JSON
+
+	is.Equal(jm.Type, "map")                         // JSON type should be `map`
+	is.Equal(jm.Orientation, m.Orientation)          // Orientation should be written
+	is.Equal(jm.RenderOrder, m.RenderOrder)          // Render order should be written
+	is.Equal(jm.Width, m.Width)                      // Width should be written
+	is.Equal(jm.Height, m.Height)                    // Height should be written
+	is.Equal(len(jm.Properties), len(*m.Properties)) // Properties should be written
+
+	props := map[string]any{}
+	for _, p := range jm.Properties {
+		props[p.Name] = p.Value
+	}
+	is.Equal(props["bool_true"], true)                                                           // Bool Property should be a JSON bool
+	is.Equal(props["pi"], 3.14)                                                                  // Float Property should be a JSON number
+	is.Equal(props["multilines"], "foo\nbar\nbaz")                                               // Multi-line Property should be a JSON string
+	is.Equal(props["my_class"], map[string]any{"MyInt": float64(22), "MyName": "my_class_name"}) // Class Property should be a JSON object
+
+	is.Equal(len(jm.Tilesets), 1)                        // Tileset should be written
+	is.Equal(jm.Tilesets[0].FirstGID, tiled.GlobalID(1)) // Tileset first GID should be written
+	is.Equal(jm.Tilesets[0].Name, "base")                // Tileset name should be written
+	is.Equal(len(jm.Tilesets[0].Tiles[4].Animation), 7)  // Tile animation should be written
+
+	var types []string
+	for _, l := range jm.Layers {
+		types = append(types, l.Type)
+	}
+	is.Equal(types, []string{"group", "objectgroup"}) // Layers should keep document order with type discriminators
+
+	group := jm.Layers[0]
+	is.Equal(group.Layers[0].Type, "imagelayer") // Group should hold its image layer
+	is.Equal(group.Layers[1].Type, "tilelayer")  // Group should hold its tile layer
+
+	layer := m.Groups.WithName("Group").TileLayers.WithName("Layer")
+	is.Equal(group.Layers[1].Encoding, "base64")  // Base64 layer should keep its encoding
+	is.Equal(group.Layers[1].Compression, "zlib") // Base64 layer should keep its compression
+
+	b, err := base64.StdEncoding.DecodeString(group.Layers[1].Data)
+	is.NoErr(err) // Error decoding base64 data
+	zr, err := zlib.NewReader(bytes.NewReader(b))
+	is.NoErr(err) // Error decompressing data
+	raw, err := io.ReadAll(zr)
+	is.NoErr(err) // Error reading data
+	want := layer.GlobalIDs()
+	is.Equal(len(raw), len(want)*4) // Layer data should hold a uint32 per tile
+	for i, gid := range want {
+		is.Equal(tiled.GlobalID(binary.LittleEndian.Uint32(raw[i*4:])), gid) // Layer data should match the GlobalIDs
+	}
+
+	for _, tc := range []struct {
+		path        string
+		compression string
+	}{
+		{"../testdata/b64deflate.tmx", "deflate"},
+		{"../testdata/b64lz4.tmx", ""},
+	} {
+		m, err := tiled.New(tc.path)
+		is.NoErr(err) // Error parsing Map
+		buf.Reset()
+		is.NoErr(m.WriteJSON(&buf)) // Error writing JSON
+		jm.Layers = nil
+		is.NoErr(json.Unmarshal(buf.Bytes(), &jm)) // Error reading written JSON
+
+		written := jm.Layers[0].Layers[1]
+		is.Equal(written.Compression, tc.compression) // Layer should be written with a compression Tiled can read
+
+		b, err := base64.StdEncoding.DecodeString(written.Data)
+		is.NoErr(err) // Error decoding base64 data
+		if tc.compression == "deflate" {
+			b, err = io.ReadAll(flate.NewReader(bytes.NewReader(b)))
+			is.NoErr(err) // Error decompressing data
+		}
+		want := m.Groups.WithName("Group").TileLayers.WithName("Layer").GlobalIDs()
+		is.Equal(len(b), len(want)*4) // Layer data should hold a uint32 per tile
+		for i, gid := range want {
+			is.Equal(tiled.GlobalID(binary.LittleEndian.Uint32(b[i*4:])), gid) // Layer data should match the GlobalIDs
+		}
+	}
+
+	unsupported, err := tiled.New("../testdata/b64zlib.tmx")
+	is.NoErr(err) // Error parsing Map
+	unsupported.Groups.WithName("Group").TileLayers.WithName("Layer").RawData.Compression = "brotli"
+	err = unsupported.WriteJSON(io.Discard)
+	is.True(errors.Is(err, tiled.ErrUnsupportedCompression))  // Unknown compression should not be written
+	is.True(strings.Contains(err.Error(), `layer "Layer": `)) // Error should name the layer
+
+	objects := jm.Layers[1]
+	is.Equal(objects.DrawOrder, tiled.TopDown)                             // Draw order should be written
+	is.Equal(objects.Objects[0].Type, "spawn")                             // Object type should be written
+	is.Equal(objects.Objects[1].Polygon[1], tiled.PointF{X: -44, Y: -197}) // Polygon points should be written
+	is.True(objects.Objects[3].Ellipse)                                    // Ellipse should be written
+	is.Equal(objects.Objects[4].Text.Text, "Hello World")                  // Text should be written
+	is.Equal(objects.Objects[4].Text.Color, "#ff0000")                     // Text color should be written
+
+	// External Tilesets and templates of the JSON resolve against ResourcePath, left at the testdata directory by New
+	for _, path := range []string{
+		"csv.tmx", "b64zlib.tmx", "b64deflate.tmx", "b64lz4.tmx", "b64zstd.tmx", "externaltileset.tmx", "terrain.tmx", "terrainpartial.tmx",
+		"wangset.tmx", "animation.tmx", "collision.tmx", "mixedgroup.tmx", "objecttemplates.tmx", "rendersize.tmx",
+		"text.tmx", "infinitecontiguous.tmx",
+	} {
+		m, err := tiled.New("../testdata/" + path)
+		is.NoErr(err) // Error parsing Map
+		buf.Reset()
+		is.NoErr(m.WriteJSON(&buf)) // Error writing JSON
+
+		var back tiled.Map
+		is.NoErr(json.Unmarshal(buf.Bytes(), &back)) // Error reading written JSON
+		_, diff := m.Equal(&back)
+		is.Equal(diff, "") // Map read back from JSON should equal the Map written
+	}
+}
+
+func TestTileLayerToCSV(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/mixedgroup.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	ground := m.TileLayers.WithName("Ground")
+	is.Equal(ground.ToCSV(), "1,2,\n3,4") // CSV should hold a row per line
+
+	is.NoErr(ground.SetTile(0, 1, tiled.GlobalID(5).WithFlips(true, false, true), m)) // Error setting tile
+	is.NoErr(ground.SetTile(1, 0, 0, m))                                              // Error clearing tile
+
+	csv := ground.ToCSV()
+	is.Equal(csv, "1,2684354565,\n0,4") // CSV should hold flip flags and empty tiles
+
+	var tl tiled.TileLayer
+	is.NoErr(xml.Unmarshal([]byte(`<layer width="2" height="2"><data encoding="csv">`+csv+`</data></layer>`), &tl)) // Error decoding CSV
+	is.Equal(len(tl.TileGlobalRefs), len(ground.TileDefs))                                                          // Decoded CSV should have a GlobalID per tile
+	for i, td := range ground.TileDefs {
+		is.Equal(tl.TileGlobalRefs[i].GlobalID, td.GlobalID) // Decoded CSV should match the TileDefs
+	}
+}
+
+func TestNewHeader(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.NewHeader("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map header
+
+	is.Equal(m.Width, 28)                                    // Map width should be decoded
+	is.Equal(m.Orientation, tiled.Orthogonal)                // Map orientation should be decoded
+	is.Equal(len(*m.Properties), 10)                         // Map Properties should be decoded
+	is.Equal(len(*m.Tilesets), 1)                            // Tilesets should be decoded
+	is.True((*m.Tilesets)[0].Tiles.WithID(6).HasAnimation()) // Tileset Tiles should be decoded
+
+	layer := m.Groups.WithName("Group").TileLayers.WithName("Layer")
+	is.Equal(layer.Width, 28)              // Layer attributes should be decoded
+	is.Equal(len(layer.TileGlobalRefs), 0) // Layer data should not be decoded
+	is.Equal(len(layer.TileDefs), 0)       // Layer TileDefs should not be decoded
+
+	_, err = layer.GetTileDefAtPosition(0, 0)
+	is.True(errors.Is(err, tiled.ErrTileDefOutOfBounds))                              // Header-only layers should have no TileDefs to access
+	is.Equal(layer.Neighbors4(0, 0), [4]*tiled.TileDef{})                             // Header-only layers should have no neighbours
+	is.Equal(layer.FloodRegion(0, 0, func(*tiled.TileDef) bool { return true }), nil) // Header-only layers should have no region
+	hit, _ := layer.Raycast(0, 0, 5, 5, func(*tiled.TileDef) bool { return true })
+	is.True(!hit) // Header-only layers should have nothing to hit
+
+	m, err = tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+	layer = m.Groups.WithName("Group").TileLayers.WithName("Layer")
+	is.Equal(len(layer.TileDefs), 28*18) // A full load should still decode layer data
+}
+
+func BenchmarkNewHeader(b *testing.B) {
+	b.Run("full", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := tiled.New("../testdata/csv.tmx"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("header", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := tiled.NewHeader("../testdata/csv.tmx"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestSkipExternalResolve(t *testing.T) {
+	is := is.New(t)
+
+	buf, err := os.ReadFile("../testdata/objecttemplates.tmx")
+	is.NoErr(err) // Error reading Map
+	path := filepath.Join(t.TempDir(), "objecttemplates.tmx")
+	is.NoErr(os.WriteFile(path, buf, 0o644)) // Error writing Map without its external files
+
+	_, err = tiled.New(path)
+	is.True(err != nil) // Missing external files should fail the load
+
+	m, err := tiled.New(path, tiled.SkipExternalResolve(true))
+	is.NoErr(err) // Error parsing Map without resolving external files
+
+	ts := (*m.Tilesets)[0]
+	is.Equal(ts.Source, "tileset.tsx")            // Tileset stub should keep its source
+	is.Equal(ts.FirstGlobalID, tiled.GlobalID(1)) // Tileset stub should keep its first GID
+	is.True(!ts.HasImage())                       // Tileset stub should not be resolved
+
+	o := m.ObjectByID(13)
+	is.Equal(o.Template, "pointtemplate.tx") // Object stub should keep its template
+	is.Equal(o.Name, "middle")               // Object stub should keep its own attributes
+	is.True(!o.IsPoint())                    // Object stub should not inherit from its template
+}
+
+// memOpener is an in-memory ResourceOpener
+type memOpener map[string][]byte
+
+func (m memOpener) Open(name string) (io.ReadCloser, error) {
+	buf, ok := m[filepath.ToSlash(name)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return io.NopCloser(bytes.NewReader(buf)), nil
+}
+
+func TestWithOpener(t *testing.T) {
+	is := is.New(t)
+
+	opener := memOpener{}
+	for _, name := range []string{"objecttemplates.tmx", "tileset.tsx", "tiletemplate.tx", "pointtemplate.tx"} {
+		buf, err := os.ReadFile(filepath.Join("../testdata", name))
+		is.NoErr(err) // Error reading fixture
+		opener["assets/"+name] = buf
+	}
+
+	m, err := tiled.New("assets/objecttemplates.tmx", tiled.WithOpener(opener))
+	is.NoErr(err) // Error parsing Map from memory
+
+	ts := (*m.Tilesets)[0]
+	is.Equal(ts.Name, "base") // External Tileset should open through the opener
+	is.True(ts.HasTiles())    // External Tileset should be resolved
+
+	delete(opener, "assets/pointtemplate.tx")
+	_, err = tiled.New("assets/objecttemplates.tmx", tiled.WithOpener(opener))
+	is.True(errors.Is(err, fs.ErrNotExist)) // Object template should open through the opener
+
+	delete(opener, "assets/tileset.tsx")
+	_, err = tiled.New("assets/objecttemplates.tmx", tiled.WithOpener(opener))
+	is.True(errors.Is(err, fs.ErrNotExist)) // Missing Tileset should report the opener error
+
+	_, err = tiled.New("assets/missing.tmx", tiled.WithOpener(opener))
+	is.True(errors.Is(err, fs.ErrNotExist)) // Map should open through the opener
+}
+
+func TestTilesetEffectiveTileCount(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/tilecount.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	atlas := m.Tilesets.WithName("atlas")
+	is.Equal(atlas.TileCount, uint32(0)) // Atlas should omit its tile count
+	columns, rows := atlas.GridSize()
+	is.Equal(columns, 3)                            // Atlas grid should have 3 columns
+	is.Equal(rows, 3)                               // Atlas grid should have 3 rows
+	is.Equal(atlas.EffectiveTileCount(), uint32(9)) // Atlas tile count should come from its grid
+
+	r, err := m.TileLayers.WithName("Ground").TileDefs[0].SourceRect()
+	is.NoErr(err)                  // Error getting tile rect without columns
+	is.Equal(r.Min, tiled.Point{}) // First tile should be at the origin
+
+	collection := m.Tilesets.WithName("collection")
+	columns, rows = collection.GridSize()
+	is.Equal(columns+rows, 0)                            // Collection should have no grid
+	is.Equal(collection.EffectiveTileCount(), uint32(3)) // Collection tile count should come from its Tiles
+	is.Equal(len(collection.Validate()), 0)              // Collection IDs should not be bound by the tile count
+
+	m, err = tiled.New("../testdata/csv.tmx")
+	is.NoErr(err)                                              // Error parsing Map
+	is.Equal((*m.Tilesets)[0].EffectiveTileCount(), uint32(9)) // Tile count attribute should be used when present
+}
+
+func TestResourceError(t *testing.T) {
+	is := is.New(t)
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		is.NoErr(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)) // Error writing fixture
+	}
+	write("broken.tmx", `<map version="1.10" orientation="orthogonal" renderorder="right-down" width="1" height="1" tilewidth="32" tileheight="32">
+ <tileset firstgid="1" source="tilesets/broken.tsx"/>
+</map>`)
+	is.NoErr(os.Mkdir(filepath.Join(dir, "tilesets"), 0o755)) // Error creating tilesets dir
+	write("tilesets/broken.tsx", `<tileset name="broken" tilewidth="32" tileheight="32"><image`)
+	write("template.tmx", `<map version="1.10" orientation="orthogonal" renderorder="right-down" width="1" height="1" tilewidth="32" tileheight="32">
+ <objectgroup id="1" name="Objects">
+  <object id="1" template="missing.tx" x="0" y="0"/>
+ </objectgroup>
+</map>`)
+
+	_, err := tiled.New(filepath.Join(dir, "broken.tmx"))
+	var re *tiled.ResourceError
+	is.True(errors.As(err, &re))                                    // Broken Tileset should report a ResourceError
+	is.Equal(re.Path, filepath.Join(dir, "tilesets", "broken.tsx")) // ResourceError should carry the Tileset path
+	is.True(errors.Is(err, tiled.ErrDecodingTileset))               // ResourceError should wrap the decoding error
+	is.True(strings.Contains(err.Error(), "broken.tsx"))            // Error message should name the Tileset file
+
+	_, err = tiled.New(filepath.Join(dir, "template.tmx"))
+	is.True(errors.As(err, &re))                        // Missing template should report a ResourceError
+	is.Equal(re.Path, filepath.Join(dir, "missing.tx")) // ResourceError should carry the template path
+	is.True(errors.Is(err, fs.ErrNotExist))             // ResourceError should wrap the open error
+}
+
+func TestObjectTileProperties(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/tileobject.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	value := func(pl *tiled.Properties, name string) string {
+		if p := pl.WithName(name); p != nil {
+			return p.Value
+		}
+		return ""
+	}
+
+	strong, err := m.ObjectByID(1).TileProperties(m)
+	is.NoErr(err)                             // Error resolving tile Object Properties
+	is.Equal(len(*strong), 3)                 // Tile and Object Properties should be merged
+	is.Equal(value(strong, "hp"), "25")       // Object Property should override the Tile Property
+	is.Equal(value(strong, "label"), "crate") // Tile Property should be inherited
+	is.Equal(value(strong, "loot"), "gold")   // Object Property should be kept
+
+	plain, err := m.ObjectByID(2).TileProperties(m)
+	is.NoErr(err)                      // Error resolving flipped tile Object Properties
+	is.Equal(value(plain, "hp"), "10") // Flipped tile Object should inherit the Tile Property
+
+	zone, err := m.ObjectByID(3).TileProperties(m)
+	is.NoErr(err)                              // Error resolving Object Properties
+	is.Equal(zone, m.ObjectByID(3).Properties) // Non-tile Object should keep its own Properties
+
+	_, err = (&tiled.Object{GlobalID: 3}).TileProperties(&tiled.Map{})
+	is.True(errors.Is(err, tiled.ErrNoSuitableTileset)) // Object without a Tileset should fail
+}
+
+func TestInfiniteContiguousData(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/infinitecontiguous.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	is.True(m.Infinite)  // Map should be infinite
+	is.Equal(m.Width, 0) // Infinite Map should have no width
+
+	ground := m.TileLayers.WithName("Ground")
+	is.Equal(ground.Width, 4)  // Layer width should be derived from the CSV rows
+	is.Equal(ground.Height, 3) // Layer height should be derived from the CSV rows
+
+	td, err := ground.GetTileDefAtPosition(1, 2)
+	is.NoErr(err)                            // Error getting tile in derived extent
+	is.Equal(td.GlobalID, tiled.GlobalID(7)) // Tile should be read at its row and column
+	_, err = ground.GetTileDefAtPosition(3, 0)
+	is.True(errors.Is(err, tiled.ErrTileDefOutOfBounds)) // Tile past the derived extent should be out of bounds
+	is.Equal(len(ground.Grid()), 3)                      // Grid should cover the derived extent
+
+	strip := m.TileLayers.WithName("Strip")
+	is.Equal(strip.Width, 0)                                              // Base64 layer has no rows to derive a width from
+	is.Equal(strip.Height, 0)                                             // Base64 layer has no rows to derive a height from
+	is.Equal(len(strip.TileDefs), 3)                                      // Base64 layer data should still be decoded
+	is.Equal(strip.GlobalIDs(), []tiled.GlobalID{1, 2, 3})                // Base64 layer data should keep its order
+	is.True(strings.Contains(m.Warnings()[len(m.Warnings())-1], "Strip")) // Unsized base64 layer should warn
+
+	_, err = strip.GetTileDefAtPosition(0, 2)
+	is.True(errors.Is(err, tiled.ErrTileDefOutOfBounds)) // Unsized layer should have no positions
+}
+
+func TestMapRemapGlobalIDs(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/twotilesets.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	ground := m.Groups.WithName("Group").TileLayers.WithName("Ground")
+	is.NoErr(ground.SetTile(1, 2, tiled.GlobalID(5).WithFlips(true, false, false), m)) // Error setting flipped tile
+
+	is.NoErr(m.RemapGlobalIDs(100)) // Error remapping GlobalIDs
+
+	is.Equal(m.Tilesets.WithName("base").FirstGlobalID, tiled.GlobalID(101))    // Tileset first GID should shift
+	is.Equal(m.Tilesets.WithName("objects").FirstGlobalID, tiled.GlobalID(119)) // Every Tileset should shift
+
+	is.Equal(ground.TileDefs[0].GlobalID, tiled.GlobalID(101)) // Tile GID should shift
+	is.True(ground.TileDefs[2].Nil)                            // Empty tile should stay empty
+	is.Equal(ground.TileDefs[2].GlobalID, tiled.GlobalID(0))   // Empty tile should keep GID 0
+
+	flipped := ground.TileDefs[5]
+	is.Equal(flipped.GlobalID.BareID(), uint32(105))  // Flipped tile GID should shift
+	is.True(flipped.GlobalID.IsFlippedHorizontally()) // Flipped tile should keep its flip flags
+	is.Equal(flipped.ID, tiled.TileID(4))             // Tile should keep its TileID
+
+	crate := m.ObjectByID(1)
+	is.Equal(crate.GlobalID, tiled.GlobalID(121))             // Tile Object GID should shift
+	is.Equal(m.TilesetForGID(crate.GlobalID).Name, "objects") // Tile Object should resolve against the shifted Tilesets
+
+	for i, td := range ground.TileDefs {
+		if td.Nil {
+			continue
+		}
+		is.Equal(m.TilesetForGID(td.GlobalID), td.TileSet) // Shifted GID should resolve to the same Tileset
+		is.Equal(td.GlobalID.TileID(td.TileSet), td.ID)    // Shifted GID should resolve to the same TileID
+		is.NoErr(ground.SetTile(i/3, i%3, td.GlobalID, m)) // Error re-resolving shifted GID
+		is.Equal(ground.TileDefs[i].Tile, td.Tile)         // Re-resolved tile should be the same Tile
+	}
+
+	// The last tile of the `objects` Tileset is now 127; the highest bare GlobalID sits just below the flip flags
+	err = m.RemapGlobalIDs(tiled.TileFlippedDiagonally - 127)
+	is.True(errors.Is(err, tiled.ErrGlobalIDOverflow))            // Shift into the flip flags should fail
+	is.Equal(crate.GlobalID, tiled.GlobalID(121))                 // Failed remap should leave tile Objects unchanged
+	is.Equal(flipped.GlobalID.BareID(), uint32(105))              // Failed remap should leave tiles unchanged
+	is.NoErr(m.RemapGlobalIDs(tiled.TileFlippedDiagonally - 128)) // Shift up to the flip flags should succeed
+	is.True(!crate.GlobalID.IsFlippedDiagonally())                // Largest shift should not set a flip flag
+}
+
+func TestWriteTemplate(t *testing.T) {
+	is := is.New(t)
+
+	tiled.ResourcePath = "../testdata"
+
+	buf, err := os.ReadFile("../testdata/tiletemplate.tx")
+	is.NoErr(err) // Error reading template
+	var tmpl tiled.Template
+	is.NoErr(xml.Unmarshal(buf, &tmpl)) // Error decoding template
+
+	text := &tiled.Template{Object: &tiled.Object{
+		Name:    "label",
+		Type:    "sign",
+		Class:   "sign",
+		Width:   64,
+		Height:  16,
+		Visible: true,
+		Properties: &tiled.Properties{
+			{Name: "size", Type: tiled.Int, Value: "3", InnerValue: "3"},
+			{Name: "note", Value: "two\nlines", InnerValue: "two\nlines"},
+		},
+		Text: &tiled.Text{FontFamily: "serif", PixelSize: 12, Color: "#ff0000", Bold: true, HAlign: tiled.HCenter,
+			VAlign: tiled.VBottom, Value: "Hello"},
+	}}
+	poly := &tiled.Template{Object: &tiled.Object{Name: "fence", Polyline: &tiled.Poly{RawPoints: "0,0 32,0 32,32"}}}
+
+	for _, tt := range []*tiled.Template{&tmpl, text, poly} {
+		var out bytes.Buffer
+		is.NoErr(tiled.WriteTemplate(&out, tt)) // Error writing template
+
+		var back tiled.Template
+		is.NoErr(xml.Unmarshal(out.Bytes(), &back)) // Error reading written template
+
+		is.Equal(*back.Object, *tt.Object) // Template Object should round-trip
+		if tt.TileSet != nil {
+			is.Equal(back.TileSet.Source, tt.TileSet.Source)               // Template Tileset source should round-trip
+			is.Equal(back.TileSet.FirstGlobalID, tt.TileSet.FirstGlobalID) // Template Tileset first GID should round-trip
+			is.Equal(back.TileSet.Name, "base")                            // Template Tileset should resolve
+		}
+	}
+	is.True(tmpl.Object.GlobalID.IsFlippedVertically()) // Template tile Object should keep its flip flags
+
+	embedded := &tiled.Template{
+		TileSet: &tiled.Tileset{FirstGlobalID: 1, Name: "inline", TileWidth: 32, TileHeight: 32},
+		Object:  &tiled.Object{GlobalID: 1, Width: 32, Height: 32, Visible: true},
+	}
+	var out bytes.Buffer
+	err = tiled.WriteTemplate(&out, embedded)
+	is.True(errors.Is(err, tiled.ErrTemplateTilesetEmbedded)) // Embedded template Tileset should be rejected
+	is.Equal(out.Len(), 0)                                    // Nothing should be written for a rejected template
+}
+
+func TestLoadTileset(t *testing.T) {
+	is := is.New(t)
+
+	ts, err := tiled.LoadTileset("../testdata/tileset.tsx")
+	is.NoErr(err) // Error loading Tileset
+
+	is.Equal(ts.Name, "base")                                 // Tileset name should be `base`
+	is.True(ts.HasImage())                                    // Tileset should have an image
+	is.Equal(ts.Image.Source, "numbers.png")                  // Tileset image source should be `numbers.png`
+	is.Equal(ts.Image.Width, 100)                             // Tileset image width should be 100
+	is.True(ts.Tiles.WithID(6).HasAnimation())                // Tile 6 should be animated
+	is.Equal(len(*ts.Tiles.WithID(0).ObjectLayer.Objects), 1) // Tile 0 should have a collision object
+
+	_, err = tiled.LoadTileset("../testdata/tileset.tsj")
+	is.True(errors.Is(err, tiled.ErrUnsupportedFormat)) // JSON tilesets should be unsupported
+}
+
+func TestLoadTemplate(t *testing.T) {
+	is := is.New(t)
+
+	tmpl, err := tiled.LoadTemplate("../testdata/tiletemplate.tx")
+	is.NoErr(err) // Error loading Template
+
+	is.Equal(tmpl.Object.Name, "tile")                  // Template Object name should be `tile`
+	is.Equal(tmpl.Object.Width, float32(128))           // Template Object width should be 128
+	is.True(tmpl.Object.GlobalID.IsFlippedVertically()) // Template Object should keep its flip flags
+	is.Equal(tmpl.TileSet.Source, "tileset.tsx")        // Template Tileset should keep its source
+	is.Equal(tmpl.TileSet.Name, "base")                 // Template Tileset should resolve from its source
+	is.True(tmpl.TileSet.HasTiles())                    // Template Tileset tiles should resolve
+
+	_, err = tiled.LoadTemplate("../testdata/tiletemplate.tj")
+	is.True(errors.Is(err, tiled.ErrUnsupportedFormat)) // JSON templates should be unsupported
+}
+
+func TestTemplateMerge(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/objecttemplates.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	objects := *(*m.ObjectLayers).WithName("Objects").Objects
+	is.Equal(objects.WithID(11).Name, "tile")                               // Object should inherit its template name
+	is.Equal(objects.WithID(11).Width, float32(128))                        // Object should inherit its template width
+	is.Equal(objects.WithID(11).Rotation, float32(45))                      // Object should inherit its template rotation
+	is.Equal(objects.WithID(11).X, float32(672))                            // Object should keep its own position
+	is.Equal(objects.WithID(11).GlobalID, tiled.GlobalID(3221225473))       // Object should inherit its template GlobalID
+	is.True(objects.WithID(12).IsPoint())                                   // Object should inherit its template shape
+	is.Equal(objects.WithID(13).Name, "middle")                             // Object should keep its own name
+	is.Equal(objects.WithID(14).Properties.WithName("what").Value, "point") // Object should inherit its template properties
+
+	m, err = tiled.New("../testdata/templaterebase.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	objects = *(*m.ObjectLayers).WithName("Objects").Objects
+	inherited := objects.WithID(1).GlobalID
+	is.Equal(inherited.BareID(), uint32(51))                 // Template GlobalID should count from the Map Tileset
+	is.True(inherited.IsFlippedHorizontally())               // Rebased GlobalID should keep its horizontal flip
+	is.True(inherited.IsFlippedVertically())                 // Rebased GlobalID should keep its vertical flip
+	is.Equal(objects.WithID(2).GlobalID, tiled.GlobalID(52)) // Object GlobalID should not be rebased
+}
+
+func TestTilesetValidateFrames(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/animation.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	errs := m.Tilesets.WithName("animated").Validate()
+	is.Equal(len(errs), 1)                                      // Animated Tileset should have 1 error
+	is.True(errors.Is(errs[0], tiled.ErrFrameTileIDOutOfRange)) // Frame tile 12 should be out of range
+	is.True(strings.Contains(errs[0].Error(), "frame tile 12")) // Error should report the frame tile
+
+	is.Equal(len(m.Warnings()), 1)                                   // Zero duration frame should warn
+	is.True(strings.Contains(m.Warnings()[0], "without a duration")) // Warning should report the missing duration
+}
+
+func TestWangSetType(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/wangset.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	wss := *(*m.Tilesets)[0].WangSets
+	corners := wss[0]
+	is.Equal(corners.Type, tiled.WangCorner) // Wang set type should be corner
+
+	tiles := *corners.WangTiles
+	colors, err := corners.Colors(tiles[0])
+	is.NoErr(err)                                    // Error parsing Wang ID
+	is.Equal(colors, [8]int{0, 1, 0, 1, 0, 2, 0, 2}) // Corner colors should be parsed
+	colors, err = corners.Colors(tiles[1])
+	is.NoErr(err)                                    // Error parsing Wang ID
+	is.Equal(colors, [8]int{0, 1, 0, 2, 0, 2, 0, 1}) // Edges of a corner set should be ignored
+
+	legacy := wss[1]
+	is.Equal(legacy.Type, tiled.WangMixed) // Wang set type should default to mixed
+	colors, err = legacy.Colors((*legacy.WangTiles)[0])
+	is.NoErr(err)                                    // Error parsing legacy Wang ID
+	is.Equal(colors, [8]int{0, 1, 0, 1, 0, 1, 0, 1}) // Legacy Wang ID should be read from top edge up
+
+	_, err = tiled.WangID("1,2,3").Colors()
+	is.True(errors.Is(err, tiled.ErrInvalidWangID)) // Short Wang ID should be invalid
+
+	var wt tiled.WangSetType
+	is.True(errors.Is(wt.UnmarshalText([]byte("diagonal")), tiled.ErrUnknownWangSetType)) // Unknown type should error
+	text, err := tiled.WangEdge.MarshalText()
+	is.NoErr(err)                  // Error marshalling Wang set type
+	is.Equal(string(text), "edge") // Wang set type should marshal to its name
+}
+
+func TestObjectEllipse(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/objecttemplates.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	o := (*(*m.ObjectLayers).WithName("Objects").Objects).WithName("ellipse")
+	is.True(o.IsEllipse()) // Object should be an ellipse
+
+	is.True(o.EllipseContains(752, 432))  // Center should be inside
+	is.True(o.EllipseContains(800, 450))  // Point near the center should be inside
+	is.True(o.EllipseContains(672, 432))  // Left boundary should be inside
+	is.True(o.EllipseContains(752, 512))  // Bottom boundary should be inside
+	is.True(!o.EllipseContains(675, 355)) // Bounding box corner should be outside
+	is.True(!o.EllipseContains(900, 432)) // Point right of the ellipse should be outside
+
+	is.Equal(o.EllipseBounds(), tiled.Rect{Min: tiled.Point{X: 672, Y: 352}, Max: tiled.Point{X: 832, Y: 512}}) // Bounds should match the Object
+
+	rotated := &tiled.Object{X: 0, Y: 0, Width: 40, Height: 20, Rotation: 90, Ellipse: &struct{}{}}
+	is.True(rotated.EllipseContains(-10, 30))                                                                    // Rotated ellipse should extend down
+	is.True(!rotated.EllipseContains(30, 10))                                                                    // Rotated ellipse should not extend right
+	is.Equal(rotated.EllipseBounds(), tiled.Rect{Min: tiled.Point{X: -20, Y: 0}, Max: tiled.Point{X: 0, Y: 40}}) // Rotated bounds should swap width and height
+
+	flat := &tiled.Object{X: 10, Y: 10, Width: 20, Ellipse: &struct{}{}}
+	is.True(!flat.EllipseContains(20, 10))                                                                     // Zero height ellipse should contain nothing
+	is.Equal(flat.EllipseBounds(), tiled.Rect{Min: tiled.Point{X: 10, Y: 10}, Max: tiled.Point{X: 30, Y: 10}}) // Zero height bounds should be flat
+}
+
+func TestTileLayerDecodeInto(t *testing.T) {
+	is := is.New(t)
+
+	tileLayers := func(m *tiled.Map) (layers []*tiled.TileLayer) {
+		_ = m.WalkLayers(func(layer any, _ []string) error {
+			if l, ok := layer.(*tiled.TileLayer); ok {
+				layers = append(layers, l)
+			}
+			return nil
+		})
+		return layers
+	}
+
+	for _, path := range []string{"../testdata/csv.tmx", "../testdata/b64zlib.tmx", "../testdata/b64zstd.tmx"} {
+		m, err := tiled.New(path)
+		is.NoErr(err) // Error parsing Map
+
+		header, err := tiled.NewHeader(path)
+		is.NoErr(err) // Error parsing Map header
+
+		headerLayers := tileLayers(header)
+		for i, l := range tileLayers(m) {
+			dst := make([]tiled.TileDef, len(l.TileDefs))
+			n, err := headerLayers[i].DecodeInto(dst, header.Tilesets)
+			is.NoErr(err)                // Error decoding into buffer
+			is.Equal(n, len(l.TileDefs)) // Decoded count should match the TileDefs
+			for j, td := range l.TileDefs {
+				is.Equal(dst[j].GlobalID, td.GlobalID)                   // Decoded GlobalID should match
+				is.Equal(dst[j].ID, td.ID)                               // Decoded TileID should match
+				is.Equal(dst[j].Nil, td.Nil)                             // Decoded empty tile should match
+				is.Equal(dst[j].TileSet == nil, td.TileSet == nil)       // Decoded Tileset should match
+				is.Equal(dst[j].Tile == nil, td.Tile == nil)             // Decoded Tile should match
+				is.Equal(dst[j].DiagonallyFlipped, td.DiagonallyFlipped) // Decoded flip flags should match
+			}
+		}
+	}
+
+	m, err := tiled.NewHeader("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map header
+	l := tileLayers(m)[0]
+	dst := make([]tiled.TileDef, 4)
+	n, err := l.DecodeInto(dst, m.Tilesets)
+	is.True(errors.Is(err, io.ErrShortBuffer)) // Short buffer should be reported
+	is.Equal(n, l.Width*l.Height)              // Count should be the length needed
+	is.Equal(len(l.TileDefs), 0)               // Layer should be left untouched
+}
+
+func TestTilesetsMerge(t *testing.T) {
+	is := is.New(t)
+
+	a, err := tiled.New("../testdata/externaltileset.tmx")
+	is.NoErr(err) // Error parsing Map
+	b, err := tiled.New("../testdata/twotilesets.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	merged := a.Tilesets.Merge(*b.Tilesets)
+	is.Equal(len(merged), 3)                              // Shared source should be kept once
+	is.True(merged[0] == (*a.Tilesets)[0])                // Tilesets should keep their own entries first
+	is.Equal(merged[1].Name, "unused")                    // Embedded Tilesets of other should follow
+	is.Equal(merged[2].Name, "objects")                   // Embedded Tilesets of other should follow
+	is.Equal(merged[2].FirstGlobalID, tiled.GlobalID(19)) // FirstGlobalIDs should be left to the caller
+
+	ts, err := tiled.LoadTileset("../testdata/tileset.tsx")
+	is.NoErr(err)                                      // Error loading Tileset
+	is.Equal(len(tiled.Tilesets{ts}.Merge(merged)), 3) // Standalone Tileset should match its loaded source
+}
+
+func TestMapParallaxOrigin(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/parallax.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	x, y := m.ParallaxOrigin()
+	is.Equal(x, float32(160))   // Parallax origin x should be 160
+	is.Equal(y, float32(-48.5)) // Parallax origin y should be -48.5
+
+	m, err = tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	x, y = m.ParallaxOrigin()
+	is.Equal(x+y, float32(0)) // Parallax origin should default to 0, 0
+}
+
+func TestMislabelledCompression(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/mislabelled.tmx")
+	is.NoErr(err) // Error parsing Map with mislabelled compression
+
+	for _, name := range []string{"Mislabelled", "Unlabelled"} {
+		l := m.TileLayers.WithName(name)
+		is.Equal(l.ToCSV(), "1,2,\n3,4") // Layer data should decode from its sniffed compression
+	}
+	is.Equal(len(m.Warnings()), 2)                                   // Each mislabelled layer should warn
+	is.True(strings.Contains(m.Warnings()[0], "is gzip compressed")) // Warning should name the sniffed compression
+
+	// A plain payload whose first GlobalID reads as the gzip magic, and which happens to be a valid gzip stream
+	var l tiled.TileLayer
+	err = xml.Unmarshal([]byte(`<layer width="9" height="1"><data encoding="base64">H4sICAAAAAAC/3h4eHgAY2RgYGACYmYgZgFiAO/UBa8QAAAA</data></layer>`), &l)
+	is.NoErr(err) // Error decoding plain layer data
+	want := []tiled.GlobalID{134777631, 0, 2021195522, 1660975224, 1616928868, 543580674, 6422886, 2936394991, 16}
+	is.Equal(l.GlobalIDs(), want) // Plain data of the declared size should not be sniffed
+}
+
+func TestTileLayerDiff(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/mixedgroup.tmx")
+	is.NoErr(err) // Error parsing Map
+	edited := m.Clone()
+
+	before := m.TileLayers.WithName("Ground")
+	after := edited.TileLayers.WithName("Ground")
+	is.NoErr(after.SetTile(0, 1, 5, edited))                                                         // Error setting tile
+	is.NoErr(after.SetTile(1, 0, 0, edited))                                                         // Error clearing tile
+	is.NoErr(after.SetTile(1, 1, before.TileDefs[3].GlobalID.WithFlips(true, false, false), edited)) // Error flipping tile
+
+	changes, err := before.Diff(after)
+	is.NoErr(err)                                                                                    // Error diffing layers
+	is.Equal(len(changes), 3)                                                                        // Three cells should differ
+	is.Equal(changes[0], tiled.TileChange{Row: 0, Col: 1, Old: before.TileDefs[1].GlobalID, New: 5}) // Replaced tile should differ
+	is.Equal(changes[1], tiled.TileChange{Row: 1, Col: 0, Old: before.TileDefs[2].GlobalID, New: 0}) // Cleared tile should differ
+	is.Equal(changes[2].New, before.TileDefs[3].GlobalID|tiled.TileFlippedHorizontally)              // Flipped tile should differ
+
+	changes, err = before.Diff(before)
+	is.NoErr(err)             // Error diffing layer with itself
+	is.Equal(len(changes), 0) // Layer should not differ from itself
+
+	_, err = before.Diff(&tiled.TileLayer{Width: 1, Height: 1})
+	is.True(errors.Is(err, tiled.ErrLayerSizeMismatch)) // Differently sized layers should not diff
+}
+
+func TestMapRequireProperties(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/objecttemplates.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	errs := m.RequireProperties(map[string]tiled.PropertyType{
+		"alt":         tiled.File,
+		"pi":          tiled.Float,
+		"bool_true":   tiled.Int,
+		"spawn_count": tiled.Int,
+	})
+	is.Equal(len(errs), 2)                                          // Two requirements should fail
+	is.True(errors.Is(errs[0], tiled.ErrPropertyWrongType))         // `bool_true` should be mistyped
+	is.True(strings.Contains(errs[0].Error(), "is bool, want int")) // Error should report both types
+	is.True(errors.Is(errs[1], tiled.ErrPropertyMissing))           // `spawn_count` should be missing
+
+	is.Equal(len(m.RequireProperties(map[string]tiled.PropertyType{"xml": tiled.String})), 0)                        // Satisfied schema should pass
+	is.Equal(len(tiled.NewMap(1, 1, 1, 1).RequireProperties(map[string]tiled.PropertyType{"xml": tiled.String})), 1) // Map without Properties should report missing
+}
+
+func TestTilesetEachTile(t *testing.T) {
+	is := is.New(t)
+
+	ts, err := tiled.LoadTileset("../testdata/tileset.tsx")
+	is.NoErr(err) // Error loading Tileset
+
+	var ids []tiled.TileID
+	explicit := 0
+	ts.EachTile(func(id tiled.TileID, tile *tiled.Tile) {
+		ids = append(ids, id)
+		if tile == nil {
+			return
+		}
+		explicit++
+		is.Equal(tile.TileID, id) // Explicit Tile should match its id
+		if id == 6 {
+			is.True(tile.HasAnimation()) // Tile 6 should be animated
+		}
+	})
+	is.Equal(ids, []tiled.TileID{0, 1, 2, 3, 4, 5, 6, 7, 8}) // Every tile id should be visited in order
+	is.Equal(explicit, len(*ts.Tiles))                       // Every explicit Tile should be passed
+
+	m, err := tiled.New("../testdata/tilecount.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	ids = nil
+	m.Tilesets.WithName("collection").EachTile(func(id tiled.TileID, tile *tiled.Tile) {
+		is.True(tile != nil) // Collection Tiles should all be explicit
+		ids = append(ids, id)
+	})
+	is.Equal(len(ids), 3) // Every collection Tile should be visited
+}
+
+func TestGroupLockedAndParent(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/mixedgroup.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	outer := m.Groups.WithName("Outer")
+	is.True(outer.Locked)        // Group `Outer` should be locked
+	is.True(outer.Parent == nil) // Top level Group should have no Parent
+
+	detail := outer.TileLayers.WithName("Detail")
+	is.True(detail.Locked)          // Layer `Detail` should be locked
+	is.True(detail.Parent == outer) // Layer `Detail` should be held by `Outer`
+
+	inner := outer.Groups.WithName("Inner")
+	is.True(!inner.Locked)                                          // Group `Inner` should not be locked
+	is.True(inner.Parent == outer)                                  // Group `Inner` should be held by `Outer`
+	is.True(inner.TileLayers.WithName("Overlay").Parent == inner)   // Layer `Overlay` should be held by `Inner`
+	is.True(inner.ImageLayers.WithName("Backdrop").Parent == inner) // Image layer `Backdrop` should be held by `Inner`
+	is.True(m.TileLayers.WithName("Ground").Parent == nil)          // Top level layer should have no Parent
+
+	clone := m.Clone().Groups.WithName("Outer")
+	is.True(clone.TileLayers.WithName("Detail").Parent == clone) // Cloned layer should be held by the cloned Group
+}
+
+func TestTileLayerUsedBounds(t *testing.T) {
+	is := is.New(t)
+
+	m := tiled.NewMap(8, 6, 32, 32)
+	m.Tilesets = &tiled.Tilesets{{FirstGlobalID: 1, Name: "base", TileWidth: 32, TileHeight: 32, TileCount: 9, Columns: 3}}
+	l := m.AddTileLayer("Ground")
+
+	_, ok := l.UsedBounds()
+	is.True(!ok) // Empty layer should have no used bounds
+
+	is.NoErr(l.SetTile(4, 5, 1, m)) // Error setting tile
+	is.NoErr(l.SetTile(5, 7, 2, m)) // Error setting tile
+	is.NoErr(l.SetTile(3, 6, 3, m)) // Error setting tile
+
+	r, ok := l.UsedBounds()
+	is.True(ok)                                                                         // Layer should have used bounds
+	is.Equal(r, tiled.Rect{Min: tiled.Point{X: 5, Y: 3}, Max: tiled.Point{X: 8, Y: 6}}) // Bounds should cover the bottom-right tiles
+}
+
+func BenchmarkDecodeCSV(b *testing.B) {
+	const width, height = 1000, 1000
+
+	var sb strings.Builder
+	for row := range height {
+		sb.WriteString("\r\n")
+		for col := range width {
+			fmt.Fprintf(&sb, "%d", (row*width+col)%9+1)
+			if row < height-1 || col < width-1 {
+				sb.WriteByte(',')
+			}
+		}
+	}
+	l := &tiled.TileLayer{Width: width, Height: height, RawData: &tiled.Data{Encoding: "csv", RawBytes: []byte(sb.String())}}
+	tss := &tiled.Tilesets{{FirstGlobalID: 1, Name: "base", TileWidth: 32, TileHeight: 32, TileCount: 9, Columns: 3}}
+	dst := make([]tiled.TileDef, width*height)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := l.DecodeInto(dst, tss); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGlobalIDString(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(tiled.GlobalID(0).String(), "0")                                     // Empty tile should be 0
+	is.Equal(tiled.GlobalID(42).String(), "42")                                   // Plain ID should have no flags
+	is.Equal(tiled.GlobalID(42).WithFlips(true, false, false).String(), "42[H]")  // Horizontal flip should be annotated
+	is.Equal(tiled.GlobalID(7).WithFlips(false, true, false).String(), "7[V]")    // Vertical flip should be annotated
+	is.Equal(tiled.GlobalID(7).WithFlips(true, true, true).String(), "7[HVD]")    // All flips should be annotated
+	is.Equal(fmt.Sprint(tiled.GlobalID(3).WithFlips(false, false, true)), "3[D]") // GlobalID should format as a Stringer
+}
+
+func TestTileLayerIndexOf(t *testing.T) {
+	is := is.New(t)
+
+	l := &tiled.TileLayer{Width: 4, Height: 3}
+	for index := range l.Width * l.Height {
+		row, col, err := l.PositionOf(index)
+		is.NoErr(err) // Error getting position of index
+		back, err := l.IndexOf(row, col)
+		is.NoErr(err)         // Error getting index of position
+		is.Equal(back, index) // Index should round-trip through its position
+	}
+
+	row, col, err := l.PositionOf(6)
+	is.NoErr(err)                          // Error getting position of index
+	is.Equal([]int{row, col}, []int{1, 2}) // Index 6 should be row 1, column 2
+
+	for _, pos := range [][2]int{{-1, 0}, {0, -1}, {3, 0}, {0, 4}} {
+		_, err := l.IndexOf(pos[0], pos[1])
+		is.True(errors.Is(err, tiled.ErrTileDefOutOfBounds)) // Position outside the layer should be out of bounds
+	}
+	for _, index := range []int{-1, 12} {
+		_, _, err := l.PositionOf(index)
+		is.True(errors.Is(err, tiled.ErrTileDefOutOfBounds)) // Index outside the layer should be out of bounds
+	}
+}
+
+func TestMapAllocIDs(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/objecttemplates.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	is.Equal(m.AllocLayerID(), tiled.LayerID(5))    // First layer ID should come from nextlayerid
+	is.Equal(m.AllocLayerID(), tiled.LayerID(6))    // Layer IDs should advance
+	is.Equal(m.NextLayerID, tiled.LayerID(7))       // Layer counter should advance
+	is.Equal(m.AllocObjectID(), tiled.ObjectID(15)) // First Object ID should come from nextobjectid
+	is.Equal(m.AllocObjectID(), tiled.ObjectID(16)) // Object IDs should advance
+	is.Equal(m.NextObjectID, 17)                    // Object counter should advance
+
+	l := m.AddTileLayer("Added")
+	is.Equal(l.ID, tiled.LayerID(7))          // Added layer should draw the next layer ID
+	is.Equal(m.NextLayerID, tiled.LayerID(8)) // Adding a layer should advance the counter
+
+	var empty tiled.Map
+	is.Equal(empty.AllocLayerID(), tiled.LayerID(1))   // Layer IDs should start at 1
+	is.Equal(empty.AllocObjectID(), tiled.ObjectID(1)) // Object IDs should start at 1
+}
+
+func TestTileDefTransform(t *testing.T) {
+	is := is.New(t)
+
+	apply := func(m [6]float32, x, y float32) [2]float32 {
+		return [2]float32{m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]}
+	}
+	corners := func(td *tiled.TileDef, w, h int) [][2]float32 {
+		m := td.Transform(w, h)
+		fw, fh := float32(w), float32(h)
+		return [][2]float32{apply(m, 0, 0), apply(m, fw, 0), apply(m, fw, fh), apply(m, 0, fh)}
+	}
+
+	// Corners are top-left, top-right, bottom-right and bottom-left of the tile image
+	is.Equal(corners(&tiled.TileDef{}, 32, 16), [][2]float32{{0, 0}, {32, 0}, {32, 16}, {0, 16}})                          // Unflipped tile should be the identity
+	is.Equal(corners(&tiled.TileDef{HorizontallyFlipped: true}, 32, 16), [][2]float32{{32, 0}, {0, 0}, {0, 16}, {32, 16}}) // Horizontal flip should mirror x
+	is.Equal(corners(&tiled.TileDef{VerticallyFlipped: true}, 32, 16), [][2]float32{{0, 16}, {32, 16}, {32, 0}, {0, 0}})   // Vertical flip should mirror y
+	is.Equal(corners(&tiled.TileDef{HorizontallyFlipped: true, VerticallyFlipped: true}, 32, 16),
+		[][2]float32{{32, 16}, {0, 16}, {0, 0}, {32, 0}}) // Both flips should rotate 180 degrees
+	is.Equal(corners(&tiled.TileDef{DiagonallyFlipped: true}, 32, 32), [][2]float32{{0, 0}, {0, 32}, {32, 32}, {32, 0}}) // Diagonal flip should swap the axes
+	is.Equal(corners(&tiled.TileDef{DiagonallyFlipped: true, HorizontallyFlipped: true}, 32, 32),
+		[][2]float32{{32, 0}, {32, 32}, {0, 32}, {0, 0}}) // Diagonal and horizontal flips should rotate 90 degrees clockwise
+	is.Equal(corners(&tiled.TileDef{DiagonallyFlipped: true, VerticallyFlipped: true}, 32, 32),
+		[][2]float32{{0, 32}, {0, 0}, {32, 0}, {32, 32}}) // Diagonal and vertical flips should rotate 270 degrees clockwise
+}
+
+func TestBOMPreamble(t *testing.T) {
+	is := is.New(t)
+
+	buf, err := os.ReadFile("../testdata/bom.tmx")
+	is.NoErr(err)                                         // Error reading fixture
+	is.True(bytes.HasPrefix(buf, []byte("\xef\xbb\xbf"))) // Fixture should start with a BOM
+
+	m, err := tiled.New("../testdata/bom.tmx")
+	is.NoErr(err)                                                  // Error parsing Map with a BOM
+	is.Equal(m.TileLayers.WithName("Ground").ID, tiled.LayerID(1)) // Map after the BOM should decode
+
+	tsx, err := os.ReadFile("../testdata/tileset.tsx")
+	is.NoErr(err) // Error reading fixture
+	opener := memOpener{"assets/tileset.tsx": append([]byte("\xef\xbb\xbf\r\n"), tsx...)}
+	ts, err := tiled.LoadTileset("assets/tileset.tsx", tiled.WithOpener(opener))
+	is.NoErr(err)             // Error loading Tileset with a BOM
+	is.Equal(ts.Name, "base") // Tileset after the BOM should decode
+}
+
+func TestObjectsWithTypeOrClass(t *testing.T) {
+	is := is.New(t)
+
+	for _, path := range []string{"../testdata/objecttype.tmx", "../testdata/objectclass.tmx"} {
+		m, err := tiled.New(path)
+		is.NoErr(err) // Error parsing Map
+
+		objects := *(*m.ObjectLayers).WithName("Objects").Objects
+		spawns := objects.WithTypeOrClass("spawn")
+		is.Equal(len(spawns), 2)                    // Both spawn Objects should match
+		is.Equal(spawns[0].Name, "start")           // Spawn Objects should keep document order
+		is.Equal(len(objects.WithClass("loot")), 1) // Class should be filled from the legacy type
+		for _, o := range objects {
+			is.Equal(o.Type, o.Class) // Type and Class should be normalised to match
+		}
+	}
+}
+
+func TestTemplateShapePrecedence(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/templateshapes.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	objects := *(*m.ObjectLayers).WithName("Objects").Objects
+	is.Equal(objects.WithName("point").Kind(), tiled.KindPoint)                   // Unsized instance should inherit the point shape
+	is.Equal(objects.WithName("rect").Kind(), tiled.KindRect)                     // Sized instance should stay a rectangle
+	is.True(!objects.WithName("rect").IsPoint())                                  // Rectangle instance should not inherit the point
+	is.Equal(objects.WithName("rect").Properties.WithName("what").Value, "point") // Rectangle instance should still inherit properties
+
+	ellipse := objects.WithName("ellipse")
+	is.True(ellipse.IsEllipse()) // Instance shape should be kept
+	is.True(!ellipse.IsPoint())  // Instance shape should not be merged with the template shape
+}
+
+func TestMapTileObjects(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/tileobject.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	var names []string
+	for _, o := range m.TileObjects() {
+		names = append(names, o.Name)
+	}
+	is.Equal(names, []string{"strong", "plain", "nested"}) // Tile Objects should be found across Groups
+
+	defs, err := m.TileObjectDefs()
+	is.NoErr(err)                                                              // Error resolving tile Objects
+	is.Equal(len(defs), 3)                                                     // Each tile Object should resolve
+	is.Equal(defs[0].TileDef.Tile.Properties.WithName("label").Value, "crate") // Tile Object should resolve its Tile
+	is.True(defs[1].TileDef.HorizontallyFlipped)                               // Flipped tile Object should keep its flip
+	is.Equal(defs[2].Object.Name, "nested")                                    // Nested tile Object should pair with its TileDef
+	is.Equal(defs[2].TileDef.ID, tiled.TileID(4))                              // Nested tile Object should resolve its TileID
+
+	is.Equal(len(tiled.NewMap(1, 1, 1, 1).TileObjects()), 0) // Map without Objects should have no tile Objects
+}
+
+func TestCollectionTileRect(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/tilecount.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	collection := m.Tilesets.WithName("collection")
+	is.Equal(collection.GetTileRectFromID(13), &tiled.Rect{Max: tiled.Point{X: 100, Y: 100}}) // Tile 3 rect should come from its own image
+	is.Equal(collection.GetTileRectFromID(10), &tiled.Rect{Max: tiled.Point{X: 896, Y: 576}}) // Tile 0 rect should come from its own image
+	is.True(collection.GetTileRectFromID(11) == nil)                                          // Missing Tile should have no rect
+
+	bare := &tiled.Tileset{FirstGlobalID: 1, TileWidth: 32, TileHeight: 32, Tiles: &tiled.Tiles{
+		{TileID: 0, Image: &tiled.Image{Source: "a.png", Width: 16, Height: 24}},
+	}}
+	is.Equal(bare.GetTileRectFromID(1), &tiled.Rect{Max: tiled.Point{X: 16, Y: 24}}) // Tileset without an image should not panic
+	is.True((&tiled.Tileset{FirstGlobalID: 1}).GetTileRectFromID(1) == nil)          // Empty Tileset should have no rect
+
+	m, err = tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	spaced := m.Tilesets.WithName("base")
+	is.Equal(spaced.GetTileRectFromID(5), &tiled.Rect{Min: tiled.Point{X: 33, Y: 33}, Max: tiled.Point{X: 65, Y: 65}}) // Atlas rect should skip the Spacing
+
+	margined := &tiled.Tileset{FirstGlobalID: 1, TileWidth: 16, TileHeight: 16, Margin: 2, Spacing: 1,
+		Image: &tiled.Image{Source: "atlas.png", Width: 54, Height: 54}}
+	is.Equal(margined.GetTileRectFromID(1), &tiled.Rect{Min: tiled.Point{X: 2, Y: 2}, Max: tiled.Point{X: 18, Y: 18}})   // Atlas rect should skip the Margin
+	is.Equal(margined.GetTileRectFromID(6), &tiled.Rect{Min: tiled.Point{X: 36, Y: 19}, Max: tiled.Point{X: 52, Y: 35}}) // Atlas rect should skip the Margin and Spacing
+	is.True(margined.GetTileRectFromID(10) == nil)                                                                       // Tile outside the image grid should have no rect
+	is.True(margined.GetTileRectFromID(0) == nil)                                                                        // Tile before the Tileset should have no rect
+}
+
+func TestTilesetRandomTile(t *testing.T) {
+	is := is.New(t)
+
+	ts := &tiled.Tileset{Tiles: &tiled.Tiles{
+		{TileID: 0, Type: "grass", Probability: 3},
+		{TileID: 1, Type: "grass"},
+		{TileID: 2, Type: "grass", Probability: 0.5},
+		{TileID: 3, Type: "water", Probability: 10},
+	}}
+	grass := func(tile *tiled.Tile) bool { return tile.Type == "grass" }
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	const draws = 45000
+	counts := map[tiled.TileID]int{}
+	for range draws {
+		tile := ts.RandomTile(rng, grass)
+		is.True(tile != nil) // Matching Tiles should be picked
+		counts[tile.TileID]++
+	}
+	is.Equal(counts[3], 0) // Filtered Tile should never be picked
+
+	// Weights 3, 1 and 0.5 out of 4.5
+	for id, want := range map[tiled.TileID]float64{0: 3 / 4.5, 1: 1 / 4.5, 2: 0.5 / 4.5} {
+		got := float64(counts[id]) / draws
+		is.True(math.Abs(got-want) < 0.01) // Picks should follow the Tile probabilities
+	}
+
+	is.True(ts.RandomTile(rng, func(*tiled.Tile) bool { return false }) == nil) // No matching Tile should pick nil
+	is.True((&tiled.Tileset{}).RandomTile(rng, nil) == nil)                     // Tileset without Tiles should pick nil
+}
+
+func TestObjectLayerColorRGBA(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/objecttemplates.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	is.Equal((*m.ObjectLayers).WithName("Objects").ColorRGBA(), color.RGBA{R: 0xaa, A: 0xff}) // Set color should be parsed
+	is.Equal((&tiled.ObjectLayer{}).ColorRGBA(), tiled.DefaultObjectColor)                    // Unset color should be the default
+	is.Equal(tiled.DefaultObjectColor, color.RGBA{R: 0xa0, G: 0xa0, B: 0xa4, A: 0xff})        // Default color should be Tiled's gray
+
+	c, err := tiled.ParseColor("#80ff0000")
+	is.NoErr(err)                             // Error parsing color
+	is.Equal(c, color.RGBA{R: 0xff, A: 0x80}) // Alpha should lead the color
+}
+
+func TestImageLayerRepeatRects(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/repeat.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	rect := func(x1, y1, x2, y2 int) tiled.Rect {
+		return tiled.Rect{Min: tiled.Point{X: x1, Y: y1}, Max: tiled.Point{X: x2, Y: y2}}
+	}
+	w, h := m.PixelSize()
+
+	clouds := m.ImageLayers.WithName("Clouds")
+	is.Equal(clouds.RepeatRects(w, h), []tiled.Rect{rect(-10, 8, 40, 48), rect(40, 8, 90, 48), rect(90, 8, 140, 48)}) // Clouds should repeat across the map
+
+	sky := m.ImageLayers.WithName("Sky")
+	is.Equal(sky.RepeatRects(w, h), []tiled.Rect{rect(0, 0, 100, 100), rect(100, 0, 200, 100)}) // Sky should repeat on both axes to cover the map
+
+	is.Equal(len((&tiled.ImageLayer{RepeatX: true}).RepeatRects(w, h)), 0) // Layer without an image should have no rects
+}
+
+func TestLoader(t *testing.T) {
+	is := is.New(t)
+
+	fsys := fstest.MapFS{}
+	for _, name := range []string{"objecttemplates.tmx", "tileset.tsx", "tiletemplate.tx", "pointtemplate.tx"} {
+		buf, err := os.ReadFile(filepath.Join("../testdata", name))
+		is.NoErr(err) // Error reading fixture
+		fsys["assets/"+name] = &fstest.MapFile{Data: buf}
+	}
+
+	loader := tiled.NewLoader(tiled.WithFS(fsys))
+	for range 2 {
+		m, err := loader.Load("assets/objecttemplates.tmx")
+		is.NoErr(err)                        // Error parsing Map from fs.FS
+		is.True((*m.Tilesets)[0].HasTiles()) // External Tileset should open through the fs.FS
+		is.True(len(*m.ObjectLayers) > 0)    // Loader should be reusable
+	}
+
+	ts, err := loader.LoadTileset("assets/tileset.tsx")
+	is.NoErr(err)             // Error parsing Tileset from fs.FS
+	is.Equal(ts.Name, "base") // Tileset should open through the fs.FS
+
+	_, err = tiled.NewLoader().Load("assets/objecttemplates.tmx")
+	is.True(errors.Is(err, fs.ErrNotExist)) // Loader without WithFS should use the OS file system
+
+	stubs := tiled.NewLoader(tiled.WithFS(fsys), tiled.SkipExternal(), tiled.HeaderOnly())
+	m, err := stubs.Load("assets/objecttemplates.tmx")
+	is.NoErr(err)                                    // Error parsing Map header
+	is.Equal((*m.Tilesets)[0].Source, "tileset.tsx") // External Tileset should be left as a stub
+	is.True(!(*m.Tilesets)[0].HasTiles())            // Stub Tileset should have no Tiles
+
+	_, err = tiled.NewLoader().Load("../testdata/unknownorientation.tmx")
+	is.True(errors.Is(err, tiled.ErrUnknownOrientation)) // Unknown orientation should fail by default
+	m, err = tiled.NewLoader(tiled.Lenient()).Load("../testdata/unknownorientation.tmx")
+	is.NoErr(err)                  // Lenient Loader should accept unknown enum values
+	is.Equal(len(m.Warnings()), 1) // Lenient Loader should record the unknown value
+
+	m, err = tiled.New("../testdata/objecttemplates.tmx")
+	is.NoErr(err)                        // New should still load without a Loader
+	is.True((*m.Tilesets)[0].HasTiles()) // New should resolve external Tilesets
+}
+
+func TestMapEqual(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/mixedgroup.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	c := m.Clone()
+	eq, diff := m.Equal(c)
+	is.True(eq)        // Map should equal its Clone
+	is.Equal(diff, "") // Equal Maps should have no difference
+
+	l := c.TileLayers.WithName("Ground")
+	l.TileDefs[0] = &tiled.TileDef{GlobalID: 99}
+	eq, diff = m.Equal(c)
+	is.True(!eq)                                                      // Changed tile should make Maps differ
+	is.Equal(diff, "Map.TileLayers[0].TileDefs[0]: GlobalID 1 != 99") // Difference should name the tile
+
+	c = m.Clone()
+	props := tiled.Properties{
+		{Name: "b", Type: tiled.String, Value: "2"},
+		{Name: "a", Type: tiled.String, Value: "1"},
+	}
+	reversed := slices.Clone(props)
+	slices.Reverse(reversed)
+	m.Properties, c.Properties = &props, &reversed
+	eq, _ = m.Equal(c)
+	is.True(eq) // Property order should not matter
+
+	reversed[0] = &tiled.Property{Name: "a", Type: tiled.String, Value: "3"}
+	eq, diff = m.Equal(c)
+	is.True(!eq)                                        // Changed Property value should make Maps differ
+	is.Equal(diff, `Map.Properties["a"].Value: 1 != 3`) // Difference should name the Property
+}
+
+func TestMultilineProperties(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/multiline.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	chardata := m.Properties.WithName("chardata")
+	attribute := m.Properties.WithName("attribute")
+	is.Equal(chardata.Value, "foo\nbar\nbaz")           // Character data should be the Property value
+	is.Equal(attribute.Value, chardata.Value)           // Attribute value with newline references should match character data
+	is.Equal(attribute.InnerValue, chardata.InnerValue) // Both storage styles should have the same inner value
+	is.Equal(chardata.InnerValue, "foo\nbar\nbaz")      // Inner value should hold the value
+
+	count, err := m.Properties.WithName("count").Int()
+	is.NoErr(err)             // Int stored as character data should convert
+	is.Equal(count, int64(7)) // Int stored as character data should be trimmed
+
+	is.Equal(m.Properties.WithName("single").InnerValue, "plain") // Attribute value should also be the inner value
+	is.Equal(m.Properties.WithName("path").Value, `C:\new\table`) // Backslashes in attribute values should be kept
+}
+
+func TestTileLayerGlobalIDs(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	l := m.Groups.WithName("Group").TileLayers.WithName("Layer")
+	gids := l.GlobalIDs()
+	is.Equal(len(gids), len(l.TileDefs)) // Should have a GlobalID per TileDef
+	for i, td := range l.TileDefs {
+		if td.Nil {
+			is.Equal(gids[i], tiled.GlobalID(0)) // Empty tile should be 0
+			continue
+		}
+		is.Equal(gids[i], td.GlobalID)                                    // GlobalID should match the TileDef
+		is.Equal(gids[i].IsFlippedHorizontally(), td.HorizontallyFlipped) // GlobalID should keep the flip flags
+	}
+
+	header, err := tiled.NewHeader("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map header
+	l = header.Groups.WithName("Group").TileLayers.WithName("Layer")
+	is.Equal(len(l.GlobalIDs()), 0) // Undecoded layer should have no GlobalIDs
+
+	l = &tiled.TileLayer{TileGlobalRefs: []*tiled.TileGlobalRef{{GlobalID: 3}, {GlobalID: 0}}}
+	is.Equal(l.GlobalIDs(), []tiled.GlobalID{3, 0}) // Layer without TileDefs should use its TileGlobalRefs
+}
+
+func TestTerrainAsWangSet(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/terrain.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	ws := (*m.Tilesets)[0].TerrainAsWangSet()
+	is.True(ws != nil)                  // Tileset with terrains should convert
+	is.Equal(ws.Type, tiled.WangCorner) // Terrains should become a corner WangSet
+
+	colors := *ws.WangColors
+	is.Equal(len(colors), 2)                    // Each terrain should become a WangColor
+	is.Equal(colors[0].Name, "Grass")           // First color should be Grass
+	is.Equal(colors[1].Name, "Water")           // Second color should be Water
+	is.Equal(colors[1].TileID, tiled.TileID(8)) // Color should keep the terrain tile
+
+	var ids []string
+	for _, wt := range *ws.WangTiles {
+		ids = append(ids, fmt.Sprintf("%d:%s", wt.TileID, wt.WangID))
+	}
+	is.Equal(ids, []string{"0:0,1,0,1,0,1,0,1", "1:0,1,0,2,0,1,0,1", "2:0,1,0,2,0,2,0,1", "8:0,2,0,2,0,2,0,2"}) // Tile terrain corners should become WangIDs
+
+	c, err := ws.Colors((*ws.WangTiles)[1])
+	is.NoErr(err)                               // Converted WangID should parse
+	is.Equal(c, [8]int{0, 1, 0, 2, 0, 1, 0, 1}) // Bottom right corner should be Water
+
+	m, err = tiled.New("../testdata/wangset.tmx")
+	is.NoErr(err)                                       // Error parsing Map
+	is.True((*m.Tilesets)[0].TerrainAsWangSet() == nil) // Tileset without terrains should not convert
+
+	m, err = tiled.New("../testdata/terrainpartial.tmx")
+	is.NoErr(err) // Partially empty terrain should not fail the load
+
+	ts := (*m.Tilesets)[0]
+	is.Equal(*ts.Tiles.WithID(3).TerrainType, tiled.TerrainType{TopLeft: 1, BottomLeft: 1}) // Empty corners should have no terrain
+	is.Equal(*ts.Tiles.WithID(4).TerrainType, tiled.TerrainType{})                          // Empty corners should have no terrain
+
+	ids = nil
+	for _, wt := range *ts.TerrainAsWangSet().WangTiles {
+		ids = append(ids, fmt.Sprintf("%d:%s", wt.TileID, wt.WangID))
+	}
+	is.Equal(ids[3:5], []string{"3:0,0,0,0,0,2,0,2", "4:0,0,0,1,0,0,0,0"}) // Empty corners should have no WangColor
+}
+
+func TestObjectBounds(t *testing.T) {
+	is := is.New(t)
+
+	rect := func(x1, y1, x2, y2 int) tiled.Rect {
+		return tiled.Rect{Min: tiled.Point{X: x1, Y: y1}, Max: tiled.Point{X: x2, Y: y2}}
+	}
+
+	tile := &tiled.Object{GlobalID: 1, X: 10, Y: 50, Width: 32, Height: 32}
+	is.Equal(tile.Bounds(), rect(10, 18, 42, 50)) // Tile Object should extend up from its bottom-left origin
+
+	tile.Rotation = 90
+	is.Equal(tile.Bounds(), rect(10, 50, 42, 82)) // Rotated tile Object should turn about its bottom-left corner
+
+	box := &tiled.Object{X: 10, Y: 50, Width: 32, Height: 16}
+	is.Equal(box.Bounds(), rect(10, 50, 42, 66)) // Rectangle should extend down from its top-left origin
+
+	box.Rotation = 90
+	is.Equal(box.Bounds(), rect(-6, 50, 10, 82)) // Rotated rectangle should turn about its top-left corner
+
+	box.Rotation = 45
+	is.Equal(box.Bounds(), rect(-2, 50, 33, 84)) // Bounds should be rounded out to whole pixels
+}
+
+func TestWalkProperties(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	var owners []any
+	var myInt *tiled.Property
+	m.WalkProperties(func(owner any, props *tiled.Properties) {
+		owners = append(owners, owner)
+		if p, ok := owner.(*tiled.Property); ok && p.Name == "my_class" {
+			myInt = props.WithName("MyInt")
+		}
+	})
+
+	is.Equal(owners[0], m)      // Map Properties should be visited first
+	is.True(myInt != nil)       // Walk should reach the members of a class Property
+	is.Equal(myInt.Value, "22") // Class member should be the nested Property
+
+	var layers int
+	for _, owner := range owners {
+		switch owner.(type) {
+		case *tiled.TileLayer, *tiled.ObjectLayer, *tiled.ImageLayer, *tiled.Group:
+			layers++
+		}
+	}
+	is.True(layers > 0) // Walk should reach layer Properties
+
+	m, err = tiled.New("../testdata/classes.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	var custom *tiled.Properties
+	m.WalkProperties(func(owner any, props *tiled.Properties) {
+		if o, ok := owner.(*tiled.Object); ok && o.Name == "custom" {
+			custom = props
+		}
+	})
+	is.True(custom != nil)                        // Walk should reach Object Properties
+	is.Equal(custom.WithName("MyInt").Value, "5") // Object Properties should be its own
+
+	var empty tiled.Map
+	empty.WalkProperties(func(any, *tiled.Properties) {
+		is.Fail() // Map without Properties should not be visited
+	})
+}
+
+func TestTileEffectiveProperties(t *testing.T) {
+	is := is.New(t)
+
+	p, err := tiled.LoadProject("../testdata/proj.tiled-project")
+	is.NoErr(err) // Error parsing Project
+
+	tile := &tiled.Tile{Type: "MyClass", Properties: &tiled.Properties{{Name: "MyInt", Type: tiled.Int, Value: "9"}}}
+	props := tile.EffectiveProperties(p)
+	is.Equal(props.WithName("MyInt").Value, "9") // Per-tile Property should override the class default
+	is.True(props.WithName("MyName") != nil)     // Class default should be inherited from the Project
+	is.Equal(len(*tile.Properties), 1)           // Tile Properties should be left untouched
+
+	plain := &tiled.Tile{Type: "Unknown", Properties: &tiled.Properties{}}
+	is.Equal(plain.EffectiveProperties(p), plain.Properties) // Tile of an undeclared class should keep its Properties
+}
+
+func TestTileLayerWithoutData(t *testing.T) {
+	is := is.New(t)
+
+	_, err := tiled.New("../testdata/nodata.tmx")
+	is.True(errors.Is(err, tiled.ErrDecodingTileLayerData)) // Layer without data should fail to decode
+	is.True(strings.Contains(err.Error(), `"Placeholder"`)) // Error should name the layer
+
+	m, err := tiled.NewHeader("../testdata/nodata.tmx")
+	is.NoErr(err)                                    // Header only load should not need layer data
+	is.Equal((*m.TileLayers)[0].Name, "Placeholder") // Layer attributes should still decode
+}
+
+func TestTilesetRenderSize(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/rendersize.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	large, plain := (*m.Tilesets)[0], (*m.Tilesets)[1]
+	is.Equal(large.TileRenderSize, tiled.RenderSizeGrid) // Tile render size should be `grid`
+	is.Equal(large.FillMode, tiled.PreserveAspectFit)    // Fill mode should be `preserve-aspect-fit`
+	is.Equal(plain.TileRenderSize, tiled.RenderSizeTile) // Tile render size should default to `tile`
+	is.Equal(plain.FillMode, tiled.Stretch)              // Fill mode should default to `stretch`
+
+	text, err := large.FillMode.MarshalText()
+	is.NoErr(err)                                 // Error marshalling fill mode
+	is.Equal(string(text), "preserve-aspect-fit") // Fill mode should marshal to its attribute value
+
+	var trs tiled.TileRenderSize
+	is.True(errors.Is(trs.UnmarshalText([]byte("huge")), tiled.ErrUnknownTileRenderSize)) // Unknown render size should fail
+	var fm tiled.FillMode
+	is.True(errors.Is(fm.UnmarshalText([]byte("crop")), tiled.ErrUnknownFillMode)) // Unknown fill mode should fail
+	_, err = tiled.FillMode(7).MarshalText()
+	is.True(errors.Is(err, tiled.ErrUnknownFillMode)) // Unknown fill mode should not marshal
+}
+
+func TestLocateTileDef(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	want := m.Groups.WithName("Group").TileLayers.WithName("Layer")
+	td, err := want.GetTileDefAtPosition(2, 3)
+	is.NoErr(err) // Error getting TileDef
+
+	layer, row, col, ok := m.LocateTileDef(td)
+	is.True(ok)           // TileDef should be found
+	is.Equal(layer, want) // TileDef should be found in its layer
+	is.Equal(row, 2)      // TileDef should be found at its row
+	is.Equal(col, 3)      // TileDef should be found at its column
+
+	_, _, _, ok = m.LocateTileDef(&tiled.TileDef{Nil: true})
+	is.True(!ok) // TileDef from outside the Map should not be found
+	_, _, _, ok = m.LocateTileDef(nil)
+	is.True(!ok) // Nil TileDef should not be found
+}
+
+func TestMapEffectiveProperties(t *testing.T) {
+	is := is.New(t)
+
+	p, err := tiled.LoadProject("../testdata/proj.tiled-project")
+	is.NoErr(err) // Error parsing Project
+	m, err := tiled.New("../testdata/mapclass.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	props := m.EffectiveProperties(p)
+	is.Equal(props.WithName("MyName").Value, "level one") // Map Property should override the class default
+	is.Equal(props.WithName("MyInt").Value, "0")          // Class default should be inherited from the Project
+	is.Equal(len(*m.Properties), 1)                       // Map Properties should be left untouched
+
+	m.Class = ""
+	is.Equal(m.EffectiveProperties(p), m.Properties) // Map without a class should keep its Properties
+}
+
+func TestReloadTileset(t *testing.T) {
+	is := is.New(t)
+
+	dir := t.TempDir()
+	for _, name := range []string{"externaltileset.tmx", "tileset.tsx"} {
+		buf, err := os.ReadFile(filepath.Join("../testdata", name))
+		is.NoErr(err)                                                // Error reading fixture
+		is.NoErr(os.WriteFile(filepath.Join(dir, name), buf, 0o644)) // Error copying fixture
+	}
+
+	m, err := tiled.New(filepath.Join(dir, "externaltileset.tmx"))
+	is.NoErr(err) // Error parsing Map
+
+	old := (*m.Tilesets)[0]
+	var td *tiled.TileDef
+	_ = m.WalkLayers(func(layer any, _ []string) error {
+		if l, ok := layer.(*tiled.TileLayer); ok && td == nil {
+			for _, d := range l.TileDefs {
+				if !d.Nil && d.ID == 0 {
+					td = d
+					break
+				}
+			}
+		}
+		return nil
+	})
+	is.True(td != nil)                                         // Map should draw tile 0
+	is.Equal(td.Tile.Properties.WithName("number").Value, "1") // Tile should have its original Property
+
+	tsx := filepath.Join(dir, "tileset.tsx")
+	buf, err := os.ReadFile(tsx)
+	is.NoErr(err) // Error reading Tileset
+	buf = bytes.Replace(buf, []byte(`name="base"`), []byte(`name="edited"`), 1)
+	buf = bytes.Replace(buf, []byte(`value="1"`), []byte(`value="100"`), 1)
+	is.NoErr(os.WriteFile(tsx, buf, 0o644)) // Error writing Tileset
+
+	is.NoErr(m.ReloadTileset("tileset.tsx")) // Error reloading Tileset
+
+	ts := (*m.Tilesets)[0]
+	is.True(ts != old)                                           // Tileset should be replaced
+	is.Equal(ts.Name, "edited")                                  // Tileset should be decoded again
+	is.Equal(ts.FirstGlobalID, old.FirstGlobalID)                // Tileset should keep its first GlobalID
+	is.Equal(td.TileSet, ts)                                     // TileDef should reference the reloaded Tileset
+	is.Equal(td.Tile.Properties.WithName("number").Value, "100") // TileDef should reference the reloaded Tile
+
+	err = m.ReloadTileset("missing.tsx")
+	is.True(errors.Is(err, tiled.ErrTilesetNotFound)) // Unknown source should fail
+
+	is.NoErr(os.WriteFile(tsx, []byte("<tileset"), 0o644)) // Error writing Tileset
+	err = m.ReloadTileset("tileset.tsx")
+	is.True(errors.Is(err, tiled.ErrDecodingTileset)) // Broken Tileset should fail to reload
+	is.Equal((*m.Tilesets)[0], ts)                    // Failed reload should leave the Map unchanged
+}
+
+func TestTilesetsSortByFirstGID(t *testing.T) {
+	is := is.New(t)
+
+	names := func(tss tiled.Tilesets) []string {
+		var ns []string
+		for _, ts := range tss {
+			ns = append(ns, ts.Name)
+		}
+		return ns
+	}
+
+	m, err := tiled.New("../testdata/tilesetorder.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	is.Equal(names(*m.Tilesets), []string{"first", "second", "third"}) // Tilesets should be sorted, ties in document order
+
+	ground := m.TileLayers.WithName("Ground")
+	is.Equal(ground.TileDefs[0].TileSet.Name, "second") // Shared first GlobalID should resolve to the last Tileset
+	is.Equal(ground.TileDefs[1].TileSet.Name, "third")  // GlobalID should resolve to its Tileset
+
+	tss := tiled.Tilesets{
+		{Name: "c", FirstGlobalID: 20},
+		{Name: "a", FirstGlobalID: 1},
+		{Name: "b1", FirstGlobalID: 5},
+		{Name: "b2", FirstGlobalID: 5},
+	}
+	tss.SortByFirstGID()
+	is.Equal(names(tss), []string{"a", "b1", "b2", "c"}) // Sort should be ascending and stable
+	tss.SortByFirstGID()
+	is.Equal(names(tss), []string{"a", "b1", "b2", "c"}) // Sorting again should not reorder ties
+}
+
+func TestObjectCollisionWorldShapes(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/collision.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	objects := m.ObjectLayers.WithName("Entities").Objects
+
+	shapes, err := objects.WithName("plain").CollisionWorldShapes(m)
+	is.NoErr(err)            // Error placing collision shapes
+	is.Equal(len(shapes), 2) // Tile should have two collision shapes
+
+	body, ramp := shapes[0], shapes[1]
+	is.Equal([]float32{body.X, body.Y, body.Width, body.Height}, []float32{104, 176, 8, 16}) // Rectangle should sit above the bottom-left origin
+	is.Equal([]float32{ramp.X, ramp.Y}, []float32{116, 184})                                 // Polygon should be translated
+	is.Equal(ramp.Polygon.RawPoints, "0,0 8,0 8,8")                                          // Unscaled polygon should keep its points
+
+	shapes, err = objects.WithName("turned").CollisionWorldShapes(m)
+	is.NoErr(err) // Error placing collision shapes
+
+	body, ramp = shapes[0], shapes[1]
+	near := func(a, b float32) bool { return math.Abs(float64(a-b)) < 1e-4 }
+	is.True(near(body.X, 124) && near(body.Y, 208))                 // Rectangle should turn about the Object origin
+	is.Equal([]float32{body.Width, body.Height}, []float32{16, 16}) // Rectangle should be scaled to the Object size
+	is.Equal(body.Rotation, float32(90))                            // Rectangle should take the Object rotation
+	is.True(near(ramp.X, 116) && near(ramp.Y, 232))                 // Polygon should turn about the Object origin
+	is.Equal(ramp.Polygon.RawPoints, "0,0 16,0 16,8")               // Polygon points should be scaled
+
+	tile := m.Tilesets.WithName("crates").Tiles.WithID(0)
+	is.Equal(tile.ObjectLayer.Objects.WithName("body").X, float32(4)) // Tile collision shapes should be left untouched
+
+	shapes, err = (&tiled.Object{X: 1, Y: 2}).CollisionWorldShapes(m)
+	is.NoErr(err)            // Object that isn't a tile should not fail
+	is.Equal(len(shapes), 0) // Object that isn't a tile should have no collision shapes
+}
+
+func TestTileAnimationFrames(t *testing.T) {
+	is := is.New(t)
+
+	m, err := tiled.New("../testdata/csv.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	ts := (*m.Tilesets)[0]
+	tile := ts.Tiles.WithID(6)
+	is.Equal(tile.Tileset, ts) // Tile should know its Tileset
+
+	frames := tile.AnimationFrames()
+	is.Equal(len(frames), 7) // Animated tile should have 7 frames
+	for i, f := range frames {
+		is.Equal(f.Frame, (*tile.Animation)[i]) // Frames should be in Animation order
+		if f.Tile != nil {
+			is.Equal(f.Tile.TileID, f.Frame.TileID) // Frame should resolve to the tile it shows
+		}
+	}
+	is.Equal(frames[0].Tile.Properties.WithName("number").Value, "1") // Frame tile Properties should be readable
+	is.Equal(frames[4].Tile.Type, "five")                             // Frame should resolve listed Tiles
+	is.Equal(frames[6].Tile, tile)                                    // Frame may show the animated tile itself
+
+	c := m.Clone()
+	cts := (*c.Tilesets)[0]
+	is.Equal(cts.Tiles.WithID(6).AnimationFrames()[0].Tile, cts.Tiles.WithID(0)) // Cloned frames should resolve in the copy
+
+	is.Equal(len(ts.Tiles.WithID(0).AnimationFrames()), 0) // Tile without an Animation should have no frames
+}
+
+func TestValidateTemplateTilesets(t *testing.T) {
+	is := is.New(t)
+
+	_, err := tiled.New("../testdata/brokentemplate.tmx")
+	var re *tiled.ResourceError
+	is.True(errors.As(err, &re))                          // Unopenable template Tileset should fail the load
+	is.Equal(filepath.Base(re.Path), "brokentemplate.tx") // Error should name the template
+	is.True(strings.Contains(err.Error(), "missing.tsx")) // Error should name the missing Tileset
+
+	m, err := tiled.New("../testdata/templatenotileset.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	errs := m.Validate()
+	is.Equal(len(errs), 1)                                        // Only the inherited GlobalID should be reported
+	is.True(errors.Is(errs[0], tiled.ErrTemplateTilesetMissing))  // Template Tileset outside the Map should be reported
+	is.True(strings.Contains(errs[0].Error(), "tiletemplate.tx")) // Error should name the template
+	is.True(strings.Contains(errs[0].Error(), "tileset.tsx"))     // Error should name the template Tileset
+
+	m, err = tiled.New("../testdata/objecttemplates.tmx")
+	is.NoErr(err)                  // Error parsing Map
+	is.Equal(len(m.Validate()), 0) // Template Tileset shared with the Map should resolve
+
+	m, err = tiled.New("../testdata/templaterebase.tmx")
+	is.NoErr(err)                  // Error parsing Map
+	is.Equal(len(m.Validate()), 0) // Template GlobalID rebased onto the Map Tileset should resolve
+
+	is.NoErr(m.RemapGlobalIDs(10)) // Error remapping GlobalIDs
+	is.Equal(len(m.Validate()), 0) // Remapping should move the template firstgid with the GlobalIDs
+
+	m.Tilesets.WithSource("tileset.tsx").FirstGlobalID = 90
+	errs = m.Validate()
+	is.Equal(len(errs), 1)                                     // Only the inherited GlobalID should be reported
+	is.True(errors.Is(errs[0], tiled.ErrTemplateTilesetMoved)) // Template Tileset at another firstgid should be reported
+	is.True(strings.Contains(errs[0].Error(), "firstgid 90"))  // Error should report the Map firstgid
+}
+
+func TestImageApplyColorKey(t *testing.T) {
+	is := is.New(t)
+
+	magenta := color.NRGBA{R: 0xff, B: 0xff, A: 0xff}
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	src := image.NewNRGBA(image.Rect(1, 1, 3, 2))
+	src.Set(1, 1, magenta)
+	src.Set(2, 1, red)
+
+	img := &tiled.Image{TransparentColor: "ff00ff"}
+	out, err := img.ApplyColorKey(src)
+	is.NoErr(err)                                            // Error applying color key
+	is.Equal(out.Bounds(), src.Bounds())                     // Copy should keep the image bounds
+	is.Equal(out.RGBAAt(1, 1), color.RGBA{})                 // Transparent color should be fully transparent
+	is.Equal(out.RGBAAt(2, 1), color.RGBA{R: 0xff, A: 0xff}) // Other colors should be kept
+	is.Equal(src.NRGBAAt(1, 1), magenta)                     // Source image should be left untouched
+
+	out, err = (&tiled.Image{}).ApplyColorKey(src)
+	is.NoErr(err)                                                     // Error copying image
+	is.Equal(out.RGBAAt(1, 1), color.RGBA{R: 0xff, B: 0xff, A: 0xff}) // Image without a transparent color should be copied as is
+
+	_, err = (&tiled.Image{TransparentColor: "nope"}).ApplyColorKey(src)
+	is.True(err != nil) // Invalid transparent color should fail
+}
+
+func TestNewCrossFormat(t *testing.T) {
+	is := is.New(t)
+
+	for _, name := range []string{"csv", "externaltileset"} {
+		tmx, err := tiled.New("../testdata/" + name + ".tmx")
+		is.NoErr(err) // TMX fixture should load
+		tmj, err := tiled.New("../testdata/" + name + ".tmj")
+		is.NoErr(err) // TMJ fixture should load
+
+		ok, diff := tmx.Equal(tmj)
+		is.Equal(diff, "") // no fields should differ between the formats
+		is.True(ok)        // both formats should decode to the same map
+	}
+	m, err := tiled.New("../testdata/objecttemplates.tmx")
+	is.NoErr(err) // Error parsing Map
+
+	objects := *(*m.ObjectLayers).WithName("Objects").Objects
+	is.True(objects.WithID(1).Visible)  // Objects should be visible unless the document says otherwise
+	is.True(!objects.WithID(7).Visible) // Template instance should keep its own visibility
+	is.True(objects.WithID(11).Visible) // Template instance should inherit the default visibility
+}
+
 func memoryUsage(m *tiled.Map, m1, m2 *runtime.MemStats) {
 	fmt.Printf("Sizeof Map: %d\n", unsafe.Sizeof(*m))
 	fmt.Println("Alloc:", m2.Alloc-m1.Alloc,
@@ -8,9 +8,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 
+	"github.com/klauspost/compress/flate"
 	"github.com/klauspost/compress/gzip"
 	"github.com/klauspost/compress/zlib"
 	"github.com/klauspost/compress/zstd"
@@ -29,10 +31,21 @@ func (tl TileLayers) WithName(name string) *TileLayer {
 	return nil
 }
 
+// WithClass retrieves all TileLayers matching the provided class. Returns `nil` if none found.
+func (tl TileLayers) WithClass(class string) TileLayers {
+	var layers TileLayers
+	for _, t := range tl {
+		if t.Class == class {
+			layers = append(layers, t)
+		}
+	}
+	return layers
+}
+
 // TileLayer aka <layer> specifies a TileLayer of a given Map; a TileLayer contains tile arrangement
 // information.
 type TileLayer struct {
-	ID        string  `xml:"id,attr"`
+	ID        LayerID `xml:"id,attr"`
 	Name      string  `xml:"name,attr"`
 	Class     string  `xml:"class,attr"`
 	X         float32 `xml:"x,attr"`
@@ -41,6 +54,7 @@ type TileLayer struct {
 	Height    int     `xml:"height,attr"`
 	Opacity   float32 `xml:"opacity,attr"`
 	Visible   bool    `xml:"visible,attr"`
+	Locked    bool    `xml:"locked,attr"`
 	TintColor string  `xml:"tintcolor,attr"`
 	OffsetX   int     `xml:"offsetx,attr"`
 	OffsetY   int     `xml:"offsety,attr"`
@@ -54,10 +68,46 @@ type TileLayer struct {
 	// Decoded data references
 	TileGlobalRefs []*TileGlobalRef
 	TileDefs       []*TileDef
+
+	// LayerData holds the decoded payload as little-endian uint32 GlobalIDs, the same bytes as a base64 payload without
+	// compression whatever the source encoding. Only set when loading with KeepLayerData.
+	LayerData []byte
+
+	// Parent is the Group holding the layer; `nil` at the top level of the Map
+	Parent *Group `xml:"-"`
+
+	// Byte offset of the end of the element within the map document, used to recover draw order
+	offset int64
+
+	// Unrecognised attributes and elements, kept so they can be written back out
+	UnknownAttrs    []xml.Attr       `xml:",any,attr"`
+	UnknownElements []UnknownElement `xml:",any"`
+}
+
+// IndexOf returns the index into TileDefs of the tile at the given row and column. Returns ErrTileDefOutOfBounds if the
+// position is outside the TileLayer.
+func (l *TileLayer) IndexOf(row, col int) (int, error) {
+	if row < 0 || row >= l.Height || col < 0 || col >= l.Width {
+		return 0, fmt.Errorf("%w: row: %d, col: %d", ErrTileDefOutOfBounds, row, col)
+	}
+	return row*l.Width + col, nil
+}
+
+// PositionOf returns the row and column of the tile at the given index into TileDefs. Returns ErrTileDefOutOfBounds if
+// the index is outside the TileLayer.
+func (l *TileLayer) PositionOf(index int) (row, col int, err error) {
+	if index < 0 || index >= l.Width*l.Height {
+		return 0, 0, fmt.Errorf("%w: index: %d", ErrTileDefOutOfBounds, index)
+	}
+	return index / l.Width, index % l.Width, nil
 }
 
 func (l *TileLayer) GetTileDefAtPosition(row, col int) (*TileDef, error) {
-	td, err := l.GetTileDefAtIndex((row * int(l.Width)) + col)
+	index, err := l.IndexOf(row, col)
+	if err != nil {
+		return nil, err
+	}
+	td, err := l.GetTileDefAtIndex(index)
 	if err != nil {
 		return nil, fmt.Errorf("%w: row: %d, col: %d", ErrTileDefOutOfBounds, row, col)
 
@@ -66,12 +116,247 @@ func (l *TileLayer) GetTileDefAtPosition(row, col int) (*TileDef, error) {
 }
 
 func (l *TileLayer) GetTileDefAtIndex(index int) (*TileDef, error) {
-	if index < 0 || index >= int(l.Width*l.Height) {
+	if index < 0 || index >= int(l.Width*l.Height) || index >= len(l.TileDefs) {
 		return nil, fmt.Errorf("%w: index: %d", ErrTileDefOutOfBounds, index)
 	}
 	return l.TileDefs[index], nil
 }
 
+// SetTile replaces the tile at the given position with a TileDef for gid, resolved against the Tilesets of the Map. A
+// gid of 0 clears the tile.
+func (l *TileLayer) SetTile(row, col int, gid GlobalID, m *Map) error {
+	if row < 0 || row >= l.Height || col < 0 || col >= l.Width || row*l.Width+col >= len(l.TileDefs) {
+		return fmt.Errorf("%w: row: %d, col: %d", ErrTileDefOutOfBounds, row, col)
+	}
+
+	td, err := newTileDef(gid, m.Tilesets)
+	if err != nil {
+		return err
+	}
+
+	l.TileDefs[row*l.Width+col] = td
+	return nil
+}
+
+// Grid returns the TileDefs as Height rows of Width columns, sharing the TileDefs of the TileLayer. Returns `nil` when
+// the TileDefs don't cover the TileLayer, such as for infinite maps stored in chunks.
+func (l *TileLayer) Grid() [][]*TileDef {
+	if l.Width <= 0 || l.Height <= 0 || len(l.TileDefs) != l.Width*l.Height {
+		return nil
+	}
+
+	grid := make([][]*TileDef, l.Height)
+	for row := range grid {
+		grid[row] = l.TileDefs[row*l.Width : (row+1)*l.Width : (row+1)*l.Width]
+	}
+	return grid
+}
+
+// UsedBounds returns the smallest Rect of cells, in columns and rows, containing every non-empty tile of the TileLayer.
+// Returns false if the TileLayer has no tiles.
+func (l *TileLayer) UsedBounds() (Rect, bool) {
+	if l.Width <= 0 {
+		return Rect{}, false
+	}
+
+	var r Rect
+	found := false
+	for i, gid := range l.GlobalIDs() {
+		if gid == 0 {
+			continue
+		}
+		col, row := i%l.Width, i/l.Width
+		if !found {
+			r = Rect{Min: Point{col, row}, Max: Point{col + 1, row + 1}}
+			found = true
+			continue
+		}
+		r = r.Union(Rect{Min: Point{col, row}, Max: Point{col + 1, row + 1}})
+	}
+	return r, found
+}
+
+// ToCSV returns the GlobalIDs of the TileLayer, including flip flags, as comma separated rows of Width columns; the
+// same form as the `csv` layer data encoding. Empty tiles are 0.
+func (l *TileLayer) ToCSV() string {
+	gids := l.GlobalIDs()
+
+	var sb strings.Builder
+	for i, gid := range gids {
+		if i > 0 {
+			sb.WriteByte(',')
+			if l.Width > 0 && i%l.Width == 0 {
+				sb.WriteByte('\n')
+			}
+		}
+		sb.WriteString(strconv.FormatUint(uint64(gid), 10))
+	}
+	return sb.String()
+}
+
+// GlobalIDs returns the grid of GlobalIDs of the TileLayer in TileDefs order, including flip flags, without the Tileset
+// and Tile lookups of TileDefs; empty tiles are 0. A Map clears the decoded TileGlobalRefs once it builds the TileDefs,
+// so the GlobalIDs are rebuilt from the TileDefs. A layer without TileDefs takes them from its TileGlobalRefs.
+func (l *TileLayer) GlobalIDs() []GlobalID {
+	if len(l.TileDefs) == 0 {
+		gids := make([]GlobalID, len(l.TileGlobalRefs))
+		for i, tgr := range l.TileGlobalRefs {
+			gids[i] = tgr.GlobalID
+		}
+		return gids
+	}
+
+	gids := make([]GlobalID, len(l.TileDefs))
+	for i, td := range l.TileDefs {
+		if td.Nil {
+			continue
+		}
+		gids[i] = td.GlobalID.WithFlips(td.HorizontallyFlipped, td.VerticallyFlipped, td.DiagonallyFlipped)
+	}
+	return gids
+}
+
+// TileHistogram counts how many times each GlobalID appears in the TileLayer. Flipped tiles are counted under their
+// flipped GlobalID; empty tiles are not counted.
+func (l *TileLayer) TileHistogram() map[GlobalID]int {
+	histogram := map[GlobalID]int{}
+	for _, td := range l.TileDefs {
+		if !td.Nil {
+			histogram[td.GlobalID]++
+		}
+	}
+	return histogram
+}
+
+// TileChange is a cell whose GlobalID differs between two TileLayers; see (*TileLayer).Diff
+type TileChange struct {
+	Row, Col int
+	Old, New GlobalID
+}
+
+// Diff returns the cells whose GlobalID, including flip flags, differs from the TileLayer to other, in row major order.
+// Returns ErrLayerSizeMismatch when the TileLayers differ in Width, Height or tile count.
+func (l *TileLayer) Diff(other *TileLayer) ([]TileChange, error) {
+	oldGIDs, newGIDs := l.GlobalIDs(), other.GlobalIDs()
+	if l.Width != other.Width || l.Height != other.Height || len(oldGIDs) != len(newGIDs) {
+		return nil, fmt.Errorf("%w: %dx%d with %d tiles, %dx%d with %d tiles", ErrLayerSizeMismatch, l.Width, l.Height,
+			len(oldGIDs), other.Width, other.Height, len(newGIDs))
+	}
+
+	var changes []TileChange
+	for i, gid := range oldGIDs {
+		if gid == newGIDs[i] {
+			continue
+		}
+		change := TileChange{Old: gid, New: newGIDs[i]}
+		if l.Width > 0 {
+			change.Row, change.Col = i/l.Width, i%l.Width
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// FloodRegion returns the positions of the cells 4-connected to the given position whose TileDefs satisfy match, as
+// Points of X column and Y row, starting with the given position. Returns `nil` if the starting cell is out of bounds
+// or doesn't match.
+func (l *TileLayer) FloodRegion(row, col int, match func(*TileDef) bool) []Point {
+	start := l.neighbor(row, col)
+	if start == nil || !match(start) {
+		return nil
+	}
+
+	visited := map[Point]bool{{col, row}: true}
+	region := []Point{{col, row}}
+	for i := 0; i < len(region); i++ {
+		p := region[i]
+		for _, n := range []Point{{p.X, p.Y - 1}, {p.X + 1, p.Y}, {p.X, p.Y + 1}, {p.X - 1, p.Y}} {
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+
+			if td := l.neighbor(n.Y, n.X); td != nil && match(td) {
+				region = append(region, n)
+			}
+		}
+	}
+	return region
+}
+
+// Raycast walks the cells crossed by the line between the centres of the from and to cells, returning the first cell
+// after the from cell whose TileDef blocks, as a Point of X column and Y row. Cells outside the TileLayer never block.
+// When nothing blocks, hit is false and at is the to cell.
+func (l *TileLayer) Raycast(fromRow, fromCol, toRow, toCol int, blocks func(*TileDef) bool) (hit bool, at Point) {
+	dx, dy := toCol-fromCol, toRow-fromRow
+	stepX, stepY := 1, 1
+	if dx < 0 {
+		stepX, dx = -1, -dx
+	}
+	if dy < 0 {
+		stepY, dy = -1, -dy
+	}
+
+	x, y := fromCol, fromRow
+	diff := dx - dy
+	for n := dx + dy; n > 0; n-- {
+		switch {
+		case diff > 0:
+			x += stepX
+			diff -= 2 * dy
+		case diff < 0:
+			y += stepY
+			diff += 2 * dx
+		default:
+			// The line passes exactly through a corner; step diagonally
+			x += stepX
+			y += stepY
+			diff += 2 * (dx - dy)
+			n--
+		}
+
+		if td := l.neighbor(y, x); td != nil && blocks(td) {
+			return true, Point{x, y}
+		}
+	}
+
+	return false, Point{toCol, toRow}
+}
+
+// Neighbors4 returns the TileDefs orthogonally adjacent to the given position, ordered north, east, south, west.
+// Entries that fall outside the TileLayer are nil.
+func (l *TileLayer) Neighbors4(row, col int) [4]*TileDef {
+	return [4]*TileDef{
+		l.neighbor(row-1, col),
+		l.neighbor(row, col+1),
+		l.neighbor(row+1, col),
+		l.neighbor(row, col-1),
+	}
+}
+
+// Neighbors8 returns the TileDefs surrounding the given position, ordered clockwise starting north: north, north-east,
+// east, south-east, south, south-west, west, north-west. Entries that fall outside the TileLayer are nil.
+func (l *TileLayer) Neighbors8(row, col int) [8]*TileDef {
+	return [8]*TileDef{
+		l.neighbor(row-1, col),
+		l.neighbor(row-1, col+1),
+		l.neighbor(row, col+1),
+		l.neighbor(row+1, col+1),
+		l.neighbor(row+1, col),
+		l.neighbor(row+1, col-1),
+		l.neighbor(row, col-1),
+		l.neighbor(row-1, col-1),
+	}
+}
+
+func (l *TileLayer) neighbor(row, col int) *TileDef {
+	td, err := l.GetTileDefAtPosition(row, col)
+	if err != nil {
+		return nil
+	}
+	return td
+}
+
 // Data represents a payload in a given Object; it may be specified in several different encodings and compressions, or as
 // a straight data structure containing TileGlobalRefs
 type Data struct {
@@ -100,6 +385,115 @@ type TileDef struct {
 	DiagonallyFlipped   bool
 }
 
+// Orientation returns the clockwise rotation in degrees (0, 90, 180 or 270) implied by the TileDef flip flags, and
+// whether the tile is mirrored horizontally before that rotation is applied.
+func (t *TileDef) Orientation() (rotationDegrees int, mirrored bool) {
+	switch {
+	case !t.DiagonallyFlipped && !t.HorizontallyFlipped && !t.VerticallyFlipped:
+		return 0, false
+	case !t.DiagonallyFlipped && t.HorizontallyFlipped && !t.VerticallyFlipped:
+		return 0, true
+	case !t.DiagonallyFlipped && !t.HorizontallyFlipped && t.VerticallyFlipped:
+		return 180, true
+	case !t.DiagonallyFlipped && t.HorizontallyFlipped && t.VerticallyFlipped:
+		return 180, false
+	case t.DiagonallyFlipped && !t.HorizontallyFlipped && !t.VerticallyFlipped:
+		return 270, true
+	case t.DiagonallyFlipped && t.HorizontallyFlipped && !t.VerticallyFlipped:
+		return 90, false
+	case t.DiagonallyFlipped && !t.HorizontallyFlipped && t.VerticallyFlipped:
+		return 270, false
+	default:
+		return 90, true
+	}
+}
+
+// Transform returns the affine transform drawing the tile image of tileW by tileH pixels flipped into its cell, as
+// [a, b, c, d, e, f] mapping image point (x, y) to (a*x + c*y + e, b*x + d*y + f), the layout of SVG and canvas
+// matrices. Flips apply in Tiled's order: the diagonal flip swaps the axes, then the horizontal and vertical flips
+// mirror within the swapped extent.
+func (t *TileDef) Transform(tileW, tileH int) [6]float32 {
+	a, b, c, d := float32(1), float32(0), float32(0), float32(1)
+	extentX, extentY := float32(tileW), float32(tileH)
+	if t.DiagonallyFlipped {
+		a, b, c, d = 0, 1, 1, 0
+		extentX, extentY = extentY, extentX
+	}
+
+	var e, f float32
+	if t.HorizontallyFlipped {
+		a, c, e = -a, -c, extentX
+	}
+	if t.VerticallyFlipped {
+		b, d, f = -b, -d, extentY
+	}
+	return [6]float32{a, b, c, d, e, f}
+}
+
+// CollisionObjects returns the Objects of the Tile ObjectLayer, used for per tile collision shapes, nil if none
+func (t *TileDef) CollisionObjects() Objects {
+	if t.Tile == nil || !t.Tile.HasObjectLayer() || t.Tile.ObjectLayer.Objects == nil {
+		return nil
+	}
+	return *t.Tile.ObjectLayer.Objects
+}
+
+// SourceRect returns the pixel Rect of the tile within its source image; the Tileset image for atlas Tilesets, or the
+// Tile image for collection Tilesets. Flips are not applied; renderers should use the flip flags or Orientation.
+func (t *TileDef) SourceRect() (*Rect, error) {
+	if t.Nil || t.TileSet == nil {
+		return nil, fmt.Errorf("%w: empty tile", ErrNoTileImage)
+	}
+
+	ts := t.TileSet
+	if t.Tile != nil && t.Tile.HasImage() {
+		w, h := t.Tile.Width, t.Tile.Height
+		if w == 0 {
+			w = t.Tile.Image.Width
+		}
+		if h == 0 {
+			h = t.Tile.Image.Height
+		}
+		return &Rect{
+			Min: Point{t.Tile.X, t.Tile.Y},
+			Max: Point{t.Tile.X + w, t.Tile.Y + h},
+		}, nil
+	}
+
+	if !ts.HasImage() || ts.TileWidth <= 0 || ts.TileHeight <= 0 {
+		return nil, fmt.Errorf("%w: tile ID %d", ErrNoTileImage, t.ID)
+	}
+
+	columns, _ := ts.GridSize()
+	if columns <= 0 {
+		return nil, fmt.Errorf("%w: tile ID %d", ErrNoTileImage, t.ID)
+	}
+
+	x := ts.Margin + (int(t.ID)%columns)*(ts.TileWidth+ts.Spacing)
+	y := ts.Margin + (int(t.ID)/columns)*(ts.TileHeight+ts.Spacing)
+	return &Rect{Min: Point{x, y}, Max: Point{x + ts.TileWidth, y + ts.TileHeight}}, nil
+}
+
+// dataExtent returns the width and height of the decoded layer data from the rows of `csv` data. Returns false when
+// the rows are uneven, or the data isn't `csv` and so has no rows to count.
+func dataExtent(l *TileLayer) (width, height int, ok bool) {
+	n := len(l.TileGlobalRefs)
+	if n == 0 || l.RawData.Encoding != "csv" {
+		return 0, 0, false
+	}
+
+	var rows int
+	for _, line := range strings.Split(strings.TrimSpace(string(l.RawData.RawBytes)), "\n") {
+		if strings.TrimSpace(line) != "" {
+			rows++
+		}
+	}
+	if rows == 0 || n%rows != 0 {
+		return 0, 0, false
+	}
+	return n / rows, rows, true
+}
+
 // GlobalID is a per-map global unique ID used in TileLayer tile definitions (tileGlobalRef). It also encodes how the
 // tile is drawn; if it's mirrored across an axis, for instance. Typically, you will not use a GlobalID directly; it
 // will be mapped for you by various helper methods on other structs.
@@ -130,6 +524,47 @@ func (g GlobalID) BareID() uint32 {
 	return uint32(g &^ TileFlipped)
 }
 
+// WithFlips returns the GlobalID with its flip flags replaced by the provided horizontal, vertical and diagonal flips
+func (g GlobalID) WithFlips(h, v, d bool) GlobalID {
+	g = g.ClearFlips()
+	if h {
+		g |= TileFlippedHorizontally
+	}
+	if v {
+		g |= TileFlippedVertically
+	}
+	if d {
+		g |= TileFlippedDiagonally
+	}
+	return g
+}
+
+// ClearFlips returns the GlobalID without tile flip information
+func (g GlobalID) ClearFlips() GlobalID {
+	return g &^ TileFlipped
+}
+
+// String returns the bare ID followed by the set flip flags in brackets, e.g. `42[H]` or `7[HVD]`; unflipped IDs are
+// just the number
+func (g GlobalID) String() string {
+	s := strconv.FormatUint(uint64(g.BareID()), 10)
+	if g&TileFlipped == 0 {
+		return s
+	}
+
+	s += "["
+	if g.IsFlippedHorizontally() {
+		s += "H"
+	}
+	if g.IsFlippedVertically() {
+		s += "V"
+	}
+	if g.IsFlippedDiagonally() {
+		s += "D"
+	}
+	return s + "]"
+}
+
 // Bitmasks for tile orientation
 const (
 	TileFlippedHorizontally = 0x80000000
@@ -140,87 +575,220 @@ const (
 
 func (l *TileLayer) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 	type tempLayer TileLayer
-	var tmp tempLayer
+	// Like Tiled, layers are visible and fully opaque unless the document says otherwise
+	tmp := tempLayer{Opacity: 1, Visible: true}
 
 	if err := xd.DecodeElement(&tmp, &start); err != nil {
 		return fmt.Errorf("%w: %w", ErrDecodingTileLayer, err)
 	}
 
 	*l = (TileLayer)(tmp)
+	l.offset = xd.InputOffset()
+
+	return l.finishDecode()
+}
+
+// finishDecode decodes the raw data of a TileLayer read from either format, unless decoding with HeaderOnly
+func (l *TileLayer) finishDecode() error {
+	if l.Width == 0 || l.Height == 0 {
+		warn("tile layer %q is missing the width and height attributes", l.Name)
+	}
+
+	if decodeOptions.headerOnly {
+		return nil
+	}
 
 	if err := decodeLayerData(l); err != nil {
 		return fmt.Errorf("%w: %w", ErrDecodingTileLayerData, err)
 	}
 
+	// Infinite maps may hold contiguous data on a layer without dimensions; derive them so position math works
+	if (l.Width == 0 || l.Height == 0) && len(l.TileGlobalRefs) > 0 {
+		if width, height, ok := dataExtent(l); ok {
+			l.Width, l.Height = width, height
+		} else {
+			warn("tile layer %q data has no rows to derive the width and height from; it is left unsized", l.Name)
+		}
+	}
+
+	if decodeOptions.keepLayerData {
+		l.LayerData = make([]byte, 0, len(l.TileGlobalRefs)*4)
+		for _, tgr := range l.TileGlobalRefs {
+			l.LayerData = binary.LittleEndian.AppendUint32(l.LayerData, uint32(tgr.GlobalID))
+		}
+	}
+
 	return nil
 }
 
-func decodeLayerData(l *TileLayer) (err error) {
-	switch l.RawData.Encoding {
+// DecodeInto decodes the raw data of the TileLayer into dst, matched with the given Tilesets, and returns the number of
+// TileDefs written. The TileLayer itself is left untouched, so a layer loaded with HeaderOnly can be decoded into a
+// buffer the caller reuses. When dst is too short it holds the leading TileDefs, the returned count is the length
+// needed and the error wraps io.ErrShortBuffer.
+func (l *TileLayer) DecodeInto(dst []TileDef, tss *Tilesets) (int, error) {
+	if l.RawData == nil {
+		return 0, nil
+	}
+
+	n := 0
+	var tdErr error
+	if err := eachGlobalID(l.RawData, l.Width*l.Height, func(gid GlobalID) {
+		if n < len(dst) && tdErr == nil {
+			dst[n], tdErr = tileDefOf(gid, tss)
+		}
+		n++
+	}); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrDecodingTileLayerData, err)
+	}
+	if tdErr != nil {
+		return 0, tdErr
+	}
+	if n > len(dst) {
+		return n, fmt.Errorf("%w: tile layer %q needs %d tile defs, got %d", io.ErrShortBuffer, l.Name, n, len(dst))
+	}
+	return n, nil
+}
+
+func decodeLayerData(l *TileLayer) error {
+	if l.RawData == nil {
+		return fmt.Errorf("tile layer %q has no data element", l.Name)
+	}
+
+	return eachGlobalID(l.RawData, l.Width*l.Height, func(gid GlobalID) {
+		l.TileGlobalRefs = append(l.TileGlobalRefs, &TileGlobalRef{
+			GlobalID: gid,
+		})
+	})
+}
+
+// decompress returns the raw layer data decompressed as the given compression attribute describes
+func decompress(raw []byte, compression string) (data []byte, err error) {
+	var r io.ReadCloser
+	switch compression {
+	case "zlib":
+		if r, err = zlib.NewReader(bytes.NewReader(raw)); err != nil {
+			return nil, err
+		}
+	case "gzip":
+		if r, err = gzip.NewReader(bytes.NewReader(raw)); err != nil {
+			return nil, err
+		}
+	case "deflate":
+		r = flate.NewReader(bytes.NewReader(raw))
+	case "zstd":
+		dd, err := zstd.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		r = dd.IOReadCloser()
+	case "lz4":
+		return decodeLZ4Frames(raw)
+	case "":
+		return raw, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCompression, compression)
+	}
+	defer func(r io.ReadCloser) {
+		err := r.Close()
+		if err != nil {
+			fmt.Printf("failed to close decode layer data reader: %s", errors.Unwrap(err))
+		}
+	}(r)
+
+	return io.ReadAll(r)
+}
+
+// sniffCompression returns the compression attribute matching the magic bytes at the start of the raw layer data, or
+// "" if none match. Deflate streams have no magic and are never reported.
+func sniffCompression(raw []byte) string {
+	switch {
+	case bytes.HasPrefix(raw, []byte{0x1f, 0x8b}):
+		return "gzip"
+	case bytes.HasPrefix(raw, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "zstd"
+	case bytes.HasPrefix(raw, []byte{0x04, 0x22, 0x4d, 0x18}):
+		return "lz4"
+	// A zlib header is a deflate CMF byte of 0x78 whose 16 bit header is a multiple of 31
+	case len(raw) >= 2 && raw[0] == 0x78 && (uint16(raw[0])<<8|uint16(raw[1]))%31 == 0:
+		return "zlib"
+	}
+	return ""
+}
+
+// eachCSVGlobalID calls fn with each GlobalID of comma separated layer data, parsing the values in place; spaces, tabs
+// and line breaks around values are ignored
+func eachCSVGlobalID(data []byte, fn func(GlobalID)) error {
+	start := 0
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && data[i] != ',' {
+			continue
+		}
+		gid, err := parseCSVGlobalID(data[start:i])
+		if err != nil {
+			return err
+		}
+		fn(gid)
+		start = i + 1
+	}
+	return nil
+}
+
+// parseCSVGlobalID parses a single decimal CSV value, reporting errors as strconv.ParseUint would
+func parseCSVGlobalID(field []byte) (GlobalID, error) {
+	field = bytes.TrimSpace(field)
+	if len(field) == 0 {
+		return 0, &strconv.NumError{Func: "ParseUint", Num: "", Err: strconv.ErrSyntax}
+	}
+
+	var v uint64
+	for _, c := range field {
+		if c < '0' || c > '9' {
+			return 0, &strconv.NumError{Func: "ParseUint", Num: string(field), Err: strconv.ErrSyntax}
+		}
+		v = v*10 + uint64(c-'0')
+		if v > math.MaxUint32 {
+			return 0, &strconv.NumError{Func: "ParseUint", Num: string(field), Err: strconv.ErrRange}
+		}
+	}
+	return GlobalID(v), nil
+}
+
+// eachGlobalID calls fn with each GlobalID of the Data payload in order; tiles is the number of tiles the layer declares
+func eachGlobalID(d *Data, tiles int, fn func(GlobalID)) (err error) {
+	switch d.Encoding {
 	case "base64":
-		b := base64.NewDecoder(base64.StdEncoding, bytes.NewReader(bytes.TrimSpace(l.RawData.RawBytes)))
+		raw, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(bytes.TrimSpace(d.RawBytes))))
+		if err != nil {
+			return err
+		}
 
-		var r io.ReadCloser
-		switch l.RawData.Compression {
-		case "zlib":
-			if r, err = zlib.NewReader(b); err != nil {
-				return err
-			}
-		case "gzip":
-			if r, err = gzip.NewReader(b); err != nil {
-				return err
+		data, err := decompress(raw, d.Compression)
+		// Some exporters mislabel the compression; the payload magic bytes tell the real one. They're only trusted
+		// when the declared compression fails or leaves the wrong amount of data, as a plain payload may start with
+		// bytes that look like magic.
+		if sniffed := sniffCompression(raw); sniffed != d.Compression && sniffed != "" &&
+			(err != nil || len(data) != 4*tiles) && !errors.Is(err, ErrUnsupportedCompression) {
+			if sdata, serr := decompress(raw, sniffed); serr == nil {
+				warn("layer data declared with compression %q is %s compressed", d.Compression, sniffed)
+				data, err = sdata, nil
 			}
-		case "zstd":
-			dd, err := zstd.NewReader(b)
-			if err != nil {
-				return err
-			}
-			defer dd.Close()
-			dc, err := io.ReadAll(dd)
-			if err != nil {
-				return err
-			}
-			r = io.NopCloser(bytes.NewReader(dc))
-		case "":
-			r = io.NopCloser(b)
-		default:
-			return fmt.Errorf("%w: %s", ErrUnsupportedCompression, l.RawData.Compression)
 		}
-		defer func(r io.ReadCloser) {
-			err := r.Close()
-			if err != nil {
-				fmt.Printf("failed to close decode layer data reader: %s", errors.Unwrap(err))
-			}
-		}(r)
-
-		var nextInt uint32
-		for {
-			err := binary.Read(r, binary.LittleEndian, &nextInt)
-			if err != nil {
-				if err == io.EOF {
-					break
-				}
-				return err
-			}
-			l.TileGlobalRefs = append(l.TileGlobalRefs, &TileGlobalRef{
-				GlobalID: GlobalID(nextInt),
-			})
+		if err != nil {
+			return err
 		}
-	case "csv":
-		for _, s := range strings.Split(string(l.RawData.RawBytes), ",") {
-			nextInt, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
-			if err != nil {
-				return err
-			}
 
-			l.TileGlobalRefs = append(l.TileGlobalRefs, &TileGlobalRef{
-				GlobalID: GlobalID(uint32(nextInt)),
-			})
+		if len(data)%4 != 0 {
+			return io.ErrUnexpectedEOF
+		}
+		for i := 0; i < len(data); i += 4 {
+			fn(GlobalID(binary.LittleEndian.Uint32(data[i:])))
 		}
+	case "csv":
+		return eachCSVGlobalID(d.RawBytes, fn)
 	case "":
 		return nil
 	default:
-		return fmt.Errorf("%w: %s", ErrUnsupportedEncoding, l.RawData.Encoding)
+		return fmt.Errorf("%w: %s", ErrUnsupportedEncoding, d.Encoding)
 	}
 
 	return nil
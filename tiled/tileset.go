@@ -1,25 +1,29 @@
 package tiled
 
 import (
+	"cmp"
 	"encoding/xml"
-	"errors"
 	"fmt"
-	"math"
-	"os"
+	"maps"
+	"math/rand/v2"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 )
 
-type byFirstGlobalID Tilesets
-
-func (a byFirstGlobalID) Len() int           { return len(a) }
-func (a byFirstGlobalID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a byFirstGlobalID) Less(i, j int) bool { return a[i].FirstGlobalID < a[j].FirstGlobalID }
-
 // Tilesets is an array of Tileset
 type Tilesets []*Tileset
 
+// SortByFirstGID sorts the Tilesets in place by ascending FirstGlobalID, the order GlobalIDs are resolved in; the
+// Tilesets of a Map are always in this order once loaded. The sort is stable, so Tilesets sharing a FirstGlobalID keep
+// their relative order, and tiles in that range resolve to the last of them.
+func (tl Tilesets) SortByFirstGID() {
+	slices.SortStableFunc(tl, func(a, b *Tileset) int {
+		return cmp.Compare(a.FirstGlobalID, b.FirstGlobalID)
+	})
+}
+
 // WithName retrieves the first Tileset matching the provided name. Returns `nil` if not found.
 func (tl Tilesets) WithName(name string) *Tileset {
 	for _, t := range tl {
@@ -30,6 +34,60 @@ func (tl Tilesets) WithName(name string) *Tileset {
 	return nil
 }
 
+// WithSource retrieves the first Tileset loaded from the provided source path. Paths are cleaned before comparison so
+// `./foo.tsx` matches `foo.tsx`. Returns `nil` if not found.
+func (tl Tilesets) WithSource(source string) *Tileset {
+	source = filepath.Clean(source)
+	for _, t := range tl {
+		if t.Source != "" && filepath.Clean(t.Source) == source {
+			return t
+		}
+	}
+	return nil
+}
+
+// WithClass retrieves all Tilesets matching the provided class. Returns `nil` if none found.
+func (tl Tilesets) WithClass(class string) Tilesets {
+	var tilesets Tilesets
+	for _, t := range tl {
+		if t.Class == class {
+			tilesets = append(tilesets, t)
+		}
+	}
+	return tilesets
+}
+
+// Merge returns the Tilesets followed by those of other not already present, keeping one entry per external Tileset
+// source. Sources are compared by the path they were loaded from, so Maps in different directories referencing the
+// same file share an entry; embedded Tilesets are always kept. FirstGlobalIDs are left unchanged for the caller to
+// assign, see (*Map).RemapGlobalIDs.
+func (tl Tilesets) Merge(other Tilesets) Tilesets {
+	merged := make(Tilesets, 0, len(tl)+len(other))
+	seen := map[string]bool{}
+	for _, t := range slices.Concat(tl, other) {
+		if key := t.sourcePath(); key != "" {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		merged = append(merged, t)
+	}
+	return merged
+}
+
+// sourcePath returns the cleaned path an external Tileset was loaded from, falling back to its Source when it was not
+// loaded through a Map. Returns "" for embedded Tilesets.
+func (t *Tileset) sourcePath() string {
+	switch {
+	case t.path != "":
+		return filepath.Clean(t.path)
+	case t.Source != "":
+		return filepath.Clean(t.Source)
+	}
+	return ""
+}
+
 // Tileset is a set of tiles, including the graphics data to be mapped to the tiles, and the actual arrangement of tiles.
 type Tileset struct {
 	FirstGlobalID   GlobalID        `xml:"firstgid,attr"`
@@ -43,6 +101,8 @@ type Tileset struct {
 	TileCount       uint32          `xml:"tilecount,attr"`
 	Columns         int             `xml:"columns,attr"`
 	ObjectAlignment ObjectAlignment `xml:"objectalignment,attr"`
+	TileRenderSize  TileRenderSize  `xml:"tilerendersize,attr"`
+	FillMode        FillMode        `xml:"fillmode,attr"`
 
 	Properties      *Properties      `xml:"properties>property"`
 	TileOffset      *tileOffset      `xml:"tileOffset"`
@@ -51,6 +111,13 @@ type Tileset struct {
 	WangSets        *WangSets        `xml:"wangsets>wangset"`
 	Tiles           *Tiles           `xml:"tile"`
 	Transformations *Transformations `xml:"transformations"`
+
+	// Unrecognised attributes and elements, kept so they can be written back out
+	UnknownAttrs    []xml.Attr       `xml:",any,attr"`
+	UnknownElements []UnknownElement `xml:",any"`
+
+	// Path the external Tileset was loaded from, Source resolved against the Map directory
+	path string
 }
 
 func (t *Tileset) HasImage() bool {
@@ -61,6 +128,117 @@ func (t *Tileset) HasTiles() bool {
 	return t.Tiles != nil
 }
 
+// GridSize returns the number of columns and rows of tiles in the Tileset image, accounting for Margin and Spacing. The
+// Columns attribute is used when present. Returns 0, 0 for collection Tilesets.
+func (t *Tileset) GridSize() (columns, rows int) {
+	if t.isCollection() || t.TileWidth <= 0 || t.TileHeight <= 0 {
+		return 0, 0
+	}
+
+	columns = t.Columns
+	if columns <= 0 {
+		columns = (t.Image.Width - 2*t.Margin + t.Spacing) / (t.TileWidth + t.Spacing)
+	}
+	rows = (t.Image.Height - 2*t.Margin + t.Spacing) / (t.TileHeight + t.Spacing)
+	return max(columns, 0), max(rows, 0)
+}
+
+// EffectiveTileCount returns the TileCount attribute when present; otherwise the number of tiles in the image grid, or
+// the number of Tiles for collection Tilesets.
+func (t *Tileset) EffectiveTileCount() uint32 {
+	if t.TileCount > 0 {
+		return t.TileCount
+	}
+	if t.isCollection() {
+		if !t.HasTiles() {
+			return 0
+		}
+		return uint32(len(*t.Tiles))
+	}
+
+	columns, rows := t.GridSize()
+	return uint32(columns * rows)
+}
+
+// EachTile calls fn for every tile of the Tileset in TileID order, from 0 to EffectiveTileCount, with the explicit Tile
+// when the Tileset lists one for the id and `nil` otherwise. Collection Tilesets may leave gaps in their ids, so only
+// their listed Tiles are visited.
+func (t *Tileset) EachTile(fn func(id TileID, tile *Tile)) {
+	byID := map[TileID]*Tile{}
+	if t.HasTiles() {
+		for _, tile := range *t.Tiles {
+			byID[tile.TileID] = tile
+		}
+	}
+
+	if t.isCollection() {
+		for _, id := range slices.Sorted(maps.Keys(byID)) {
+			fn(id, byID[id])
+		}
+		return
+	}
+
+	for id := range TileID(t.EffectiveTileCount()) {
+		fn(id, byID[id])
+	}
+}
+
+// RandomTile picks one of the Tileset's Tiles matching filter, weighted by Probability as Tiled's random mode does; an
+// unset Probability of 0 weighs 1. A `nil` filter matches every Tile. Returns `nil` if no Tile matches.
+func (t *Tileset) RandomTile(rng *rand.Rand, filter func(*Tile) bool) *Tile {
+	if !t.HasTiles() {
+		return nil
+	}
+
+	var candidates []*Tile
+	var weights []float64
+	total := 0.0
+	for _, tile := range *t.Tiles {
+		if filter != nil && !filter(tile) {
+			continue
+		}
+		w := float64(tile.Probability)
+		if w == 0 {
+			w = 1
+		}
+		if w < 0 {
+			continue
+		}
+		candidates = append(candidates, tile)
+		weights = append(weights, w)
+		total += w
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	pick := rng.Float64() * total
+	for i, w := range weights {
+		if pick < w {
+			return candidates[i]
+		}
+		pick -= w
+	}
+	// Rounding may leave pick just past the last weight
+	return candidates[len(candidates)-1]
+}
+
+// isCollection reports whether the Tileset is a collection of images; decoding lends a collection the image of one of
+// its Tiles, so it is one when it has no image or shares the image of a Tile.
+func (t *Tileset) isCollection() bool {
+	if !t.HasImage() {
+		return true
+	}
+	if t.HasTiles() {
+		for _, tile := range *t.Tiles {
+			if tile.Image != nil && tile.Image == t.Image {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (t *Tileset) GetTileRect(tile *Tile) *Rect {
 	return &Rect{
 		Min: Point{int(tile.X), int(tile.Y)},
@@ -68,34 +246,48 @@ func (t *Tileset) GetTileRect(tile *Tile) *Rect {
 	}
 }
 
+// GetTileRectFromID returns the source Rect of the tile with the given bare GlobalID, as TileDef.SourceRect does; within
+// the Tileset image, honouring its Margin and Spacing, for atlas Tilesets, or within the Tile image for collection
+// Tilesets. Returns `nil` if the tile has no image or lies outside the image grid.
 func (t *Tileset) GetTileRectFromID(bareID uint32) *Rect {
-	bID := int(bareID)
-	fGID := int(t.FirstGlobalID)
-	w := int(t.TileWidth)
-	h := int(t.TileHeight)
-	iw := int(t.Image.Width)
-	ih := float64(t.Image.Height)
-	count := int(math.Floor(float64(iw)/float64(w)) * math.Floor(ih/float64(h)))
+	if bareID < uint32(t.FirstGlobalID) {
+		return nil
+	}
+	id := TileID(bareID - uint32(t.FirstGlobalID))
 
-	var tileHor = 0
-	var tileVert = 0
-	for i := 0; i < count; i++ {
-		if i == bID-fGID {
-			x := tileHor * w
-			y := tileVert * h
-			return &Rect{Point{x, y}, Point{x + w, y + h}}
+	// Collections have no atlas to divide; each Tile has its own image
+	if t.isCollection() {
+		if !t.HasTiles() {
+			return nil
 		}
+	} else if columns, rows := t.GridSize(); int(id) >= columns*rows {
+		return nil
+	}
 
-		// Update x and y position
-		tileHor++
-
-		if tileHor == iw/w {
-			tileHor = 0
-			tileVert++
-		}
+	td := &TileDef{ID: id, GlobalID: GlobalID(bareID), TileSet: t}
+	if t.HasTiles() {
+		td.Tile = t.Tiles.WithID(id)
 	}
+	r, err := td.SourceRect()
+	if err != nil {
+		return nil
+	}
+	return r
+}
 
-	return nil
+// FrameGlobalID returns the GlobalID of the tile shown by an animation Frame of one of the Tileset's Tiles
+func (t *Tileset) FrameGlobalID(f *Frame) GlobalID {
+	return t.FirstGlobalID + GlobalID(f.TileID)
+}
+
+// FrameRect returns the source rectangle of the tile shown by an animation Frame of one of the Tileset's Tiles; see
+// (*TileDef).SourceRect.
+func (t *Tileset) FrameRect(f *Frame) (*Rect, error) {
+	td := &TileDef{ID: f.TileID, GlobalID: t.FrameGlobalID(f), TileSet: t}
+	if t.HasTiles() {
+		td.Tile = t.Tiles.WithID(f.TileID)
+	}
+	return td.SourceRect()
 }
 
 // Tiles is an array of Tile
@@ -131,6 +323,36 @@ type Tile struct {
 	ObjectLayer *ObjectLayer `xml:"objectgroup"`
 
 	TerrainType *TerrainType
+
+	// Tileset is the Tileset listing the Tile; `nil` for Tiles built outside a Tileset
+	Tileset *Tileset `xml:"-"`
+
+	// Unrecognised attributes and elements, kept so they can be written back out
+	UnknownAttrs    []xml.Attr       `xml:",any,attr"`
+	UnknownElements []UnknownElement `xml:",any"`
+}
+
+// AnimationFrame is a Frame of a Tile Animation with the Tile it shows, `nil` if the Tileset doesn't list that tile
+type AnimationFrame struct {
+	Frame *Frame
+	Tile  *Tile
+}
+
+// AnimationFrames returns the Frames of the Tile Animation with the Tiles they show, looked up in the Tileset of the
+// Tile, so the Properties of each frame tile can be read. Returns `nil` if the Tile has no Animation.
+func (t *Tile) AnimationFrames() []AnimationFrame {
+	if !t.HasAnimation() {
+		return nil
+	}
+
+	frames := make([]AnimationFrame, len(*t.Animation))
+	for i, f := range *t.Animation {
+		frames[i].Frame = f
+		if t.Tileset != nil && t.Tileset.HasTiles() {
+			frames[i].Tile = t.Tileset.Tiles.WithID(f.TileID)
+		}
+	}
+	return frames
 }
 
 func (t *Tile) HasImage() bool {
@@ -149,6 +371,13 @@ func (t *Tile) HasTerrainType() bool {
 	return t.TerrainType != nil
 }
 
+// EffectiveProperties returns the Tile Properties merged over the defaults of the class named by the Tile type: those
+// the Project declares, or with a `nil` Project those registered with RegisterClass. Returns the Tile Properties when
+// the class is unknown or its defaults fail to resolve.
+func (t *Tile) EffectiveProperties(proj *Project) *Properties {
+	return inheritClass(t.Properties, t.Type, proj)
+}
+
 // Terrain defines a type of terrain and its associated tile ID. [Deprecated]
 type Terrain struct {
 	Name       string      `xml:"name,attr"`
@@ -156,7 +385,8 @@ type Terrain struct {
 	Properties *Properties `xml:"properties>property"`
 }
 
-// TerrainType represents the unique corner tiles used by a particular terrain. [Deprecated]
+// TerrainType represents the unique corner tiles used by a particular terrain; corners left empty in the terrain
+// attribute read as 0. [Deprecated]
 type TerrainType struct {
 	TopLeft     TileID
 	TopRight    TileID
@@ -164,6 +394,51 @@ type TerrainType struct {
 	BottomRight TileID
 }
 
+// TerrainAsWangSet converts the deprecated TerrainTypes of the Tileset to a corner WangSet, as Tiled does when opening
+// legacy content. Each Terrain becomes the WangColor one past its index, since 0 means no color, and each Tile with a
+// terrain attribute becomes a WangTile with its corners set. Returns `nil` if the Tileset has no TerrainTypes.
+func (t *Tileset) TerrainAsWangSet() *WangSet {
+	if t.TerrainTypes == nil || len(*t.TerrainTypes) == 0 {
+		return nil
+	}
+
+	colors := make([]*WangColor, len(*t.TerrainTypes))
+	for i, terrain := range *t.TerrainTypes {
+		colors[i] = &WangColor{Name: terrain.Name, TileID: terrain.TileID, Properties: terrain.Properties}
+	}
+
+	tiles := []*WangTile{}
+	if t.HasTiles() {
+		for _, tile := range *t.Tiles {
+			if tile.RawTerrainType == "" || tile.TerrainType == nil {
+				continue
+			}
+			// WangIDs run clockwise from the top edge, with the corners at the odd positions; an empty corner has no color
+			corners := strings.Split(tile.RawTerrainType, ",")
+			color := func(i int, id TileID) TileID {
+				if i >= len(corners) || strings.TrimSpace(corners[i]) == "" {
+					return 0
+				}
+				return id + 1
+			}
+			tt := tile.TerrainType
+			tiles = append(tiles, &WangTile{
+				TileID: tile.TileID,
+				WangID: WangID(fmt.Sprintf("0,%d,0,%d,0,%d,0,%d",
+					color(1, tt.TopRight), color(3, tt.BottomRight), color(2, tt.BottomLeft), color(0, tt.TopLeft))),
+			})
+		}
+	}
+
+	return &WangSet{
+		Name:       "Terrains",
+		Type:       WangCorner,
+		TileID:     (*t.TerrainTypes)[0].TileID,
+		WangColors: &colors,
+		WangTiles:  &tiles,
+	}
+}
+
 // Animation is an array for frame Objects
 type Animation []*Frame
 
@@ -173,28 +448,62 @@ type Frame struct {
 	DurationMsec int    `xml:"duration,attr"`
 }
 
+// Rect is an axis aligned rectangle containing the points with Min.X <= X < Max.X, Min.Y <= Y < Max.Y
 type Rect struct {
 	Min Point
 	Max Point
 }
 
+// Width returns the width of the Rect
+func (r Rect) Width() int {
+	return r.Max.X - r.Min.X
+}
+
+// Height returns the height of the Rect
+func (r Rect) Height() int {
+	return r.Max.Y - r.Min.Y
+}
+
+// Size returns the width and height of the Rect
+func (r Rect) Size() Point {
+	return Point{r.Width(), r.Height()}
+}
+
+// Intersects returns true if the Rect and o share any point; touching edges do not intersect
+func (r Rect) Intersects(o Rect) bool {
+	return r.Min.X < o.Max.X && o.Min.X < r.Max.X && r.Min.Y < o.Max.Y && o.Min.Y < r.Max.Y
+}
+
+// ContainsPoint returns true if p lies within the Rect
+func (r Rect) ContainsPoint(p Point) bool {
+	return r.Min.X <= p.X && p.X < r.Max.X && r.Min.Y <= p.Y && p.Y < r.Max.Y
+}
+
+// Union returns the smallest Rect containing both the Rect and o
+func (r Rect) Union(o Rect) Rect {
+	return Rect{
+		Min: Point{min(r.Min.X, o.Min.X), min(r.Min.Y, o.Min.Y)},
+		Max: Point{max(r.Max.X, o.Max.X), max(r.Max.Y, o.Max.Y)},
+	}
+}
+
 type tileOffset struct {
-	X int `xml:"x,attr"`
-	Y int `xml:"y,attr"`
+	X int `xml:"x,attr" json:"x"`
+	Y int `xml:"y,attr" json:"y"`
 }
 
 // Transformations describes which transformations can be applied to the tiles (e.g. to extend a Wang set by
 // transforming existing tiles).
 type Transformations struct {
 	// Whether the tiles in this set can be flipped horizontally (default 0)
-	HFlip bool `xml:"hflip,attr"`
+	HFlip bool `xml:"hflip,attr" json:"hflip"`
 	// Whether the tiles in this set can be flipped vertically (default 0)
-	VFlip bool `xml:"vflip,attr"`
+	VFlip bool `xml:"vflip,attr" json:"vflip"`
 	// Whether the tiles in this set can be rotated in 90 degree increments (default 0)
-	Rotate bool `xml:"rotate,attr"`
+	Rotate bool `xml:"rotate,attr" json:"rotate"`
 	// Whether untransformed tiles remain preferred, otherwise transformed tiles are used to produce more variations
 	// (default 0)
-	PreferUntransformed bool `xml:"preferUntransformed,attr"`
+	PreferUntransformed bool `xml:"preferUntransformed,attr" json:"preferuntransformed"`
 }
 
 // WangSets is an array of wangSet Objects
@@ -202,9 +511,10 @@ type WangSets []*WangSet
 
 // WangSet Defines a list of colors and any number of Wang tiles using these colors.
 type WangSet struct {
-	Name   string `xml:"name,attr"`
-	Class  string `xml:"class,attr"`
-	TileID TileID `xml:"tile,attr"`
+	Name   string      `xml:"name,attr"`
+	Class  string      `xml:"class,attr"`
+	Type   WangSetType `xml:"type,attr"`
+	TileID TileID      `xml:"tile,attr"`
 
 	Properties *Properties   `xml:"properties>property"`
 	WangColors *[]*WangColor `xml:"wangcolor"`
@@ -221,13 +531,70 @@ type WangColor struct {
 	Properties *Properties `xml:"properties>property"`
 }
 
+// WangSetType determines which positions of a WangID a WangSet uses
+type WangSetType int
+
+const (
+	WangMixed WangSetType = iota
+	WangCorner
+	WangEdge
+)
+
 type WangID string
 
+// Colors returns the color indices of the WangID, clockwise from the top edge: top, top right, right, bottom right,
+// bottom, bottom left, left, top left. Both the comma separated format and the legacy 0xCECECECE format are read.
+func (w WangID) Colors() ([8]int, error) {
+	var colors [8]int
+
+	s := strings.TrimSpace(string(w))
+	if hex, ok := strings.CutPrefix(s, "0x"); ok {
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return colors, fmt.Errorf("%w: %s", ErrInvalidWangID, w)
+		}
+		for i := range colors {
+			colors[i] = int(v >> (4 * i) & 0xF)
+		}
+		return colors, nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != len(colors) {
+		return colors, fmt.Errorf("%w: %s", ErrInvalidWangID, w)
+	}
+	for i, p := range parts {
+		c, err := strconv.ParseUint(strings.TrimSpace(p), 10, 8)
+		if err != nil {
+			return colors, fmt.Errorf("%w: %s", ErrInvalidWangID, w)
+		}
+		colors[i] = int(c)
+	}
+	return colors, nil
+}
+
+// Colors returns the color indices of the WangTile as WangID.Colors, with the positions the WangSet Type does not use
+// (edges of a corner set, corners of an edge set) left at 0.
+func (ws *WangSet) Colors(wt *WangTile) ([8]int, error) {
+	colors, err := wt.WangID.Colors()
+	if err != nil {
+		return colors, err
+	}
+
+	for i := range colors {
+		// Even positions are edges and odd positions are corners
+		corner := i%2 == 1
+		if ws.Type == WangCorner && !corner || ws.Type == WangEdge && corner {
+			colors[i] = 0
+		}
+	}
+	return colors, nil
+}
+
 type WangTile struct {
 	Name   string `xml:"name,attr"`
 	TileID TileID `xml:"tileid,attr"`
-	// WangID is a 32-bit unsigned integer stored in the format 0xCECECECE where C is a corner color and each E is an
-	// edge color, from right to left clockwise, starting with the top edge.
+	// WangID holds a color index per corner and edge, clockwise from the top edge; see WangID.Colors
 	WangID WangID `xml:"wangid,attr"`
 }
 
@@ -246,6 +613,23 @@ const (
 	BottomRight
 )
 
+// TileRenderSize is the size tiles of a Tileset are drawn at: their own size, or the tile grid size of the Map
+type TileRenderSize int
+
+const (
+	RenderSizeTile TileRenderSize = iota
+	RenderSizeGrid
+)
+
+// FillMode is how tiles drawn at a size other than their own are scaled: stretched to fill it, or scaled to fit within
+// it keeping their aspect ratio
+type FillMode int
+
+const (
+	Stretch FillMode = iota
+	PreserveAspectFit
+)
+
 func (t *Tileset) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 	type tempTileSet Tileset
 	var tmp tempTileSet
@@ -254,34 +638,69 @@ func (t *Tileset) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 		return fmt.Errorf("%w: %w", ErrDecodingTileset, err)
 	}
 
-	firstGlobalID := tmp.FirstGlobalID
 	*t = (Tileset)(tmp)
 
-	if tmp.Source == "" {
+	return t.finishDecode()
+}
+
+// finishDecode completes a Tileset read from either format: an embedded Tileset adopts its Tiles, and an external one
+// is loaded from its Source unless decoding with SkipExternalResolve
+func (t *Tileset) finishDecode() error {
+	if t.Source == "" {
+		t.warnDeprecated()
+		t.adoptTiles()
+		return nil
+	}
+	path := filepath.Join(ResourcePath, t.Source)
+	t.path = path
+	if decodeOptions.skipExternal {
 		return nil
 	}
 
-	path := filepath.Join(ResourcePath, tmp.Source)
-	f, err := os.Open(path)
+	return t.loadExternal(path, t.FirstGlobalID)
+}
+
+// loadExternal decodes the Tileset from the external tileset file at path, keeping the firstGlobalID the Map assigns it
+func (t *Tileset) loadExternal(path string, firstGlobalID GlobalID) error {
+	type tempTileSet Tileset
+	tmp := tempTileSet(*t)
+
+	buf, err := readResource(path, "Tileset")
 	if err != nil {
-		return fmt.Errorf("failed to open Tileset file: %w", err)
+		return &ResourceError{Path: path, Err: err}
 	}
-	defer func(f *os.File) {
-		err := f.Close()
-		if err != nil {
-			fmt.Printf("error closing Tileset file handler %s", errors.Unwrap(err))
-		}
-	}(f)
 
-	if err := xml.NewDecoder(f).Decode(&tmp); err != nil {
-		return fmt.Errorf("%w: %w", ErrDecodingTileset, err)
+	if err := xml.Unmarshal(buf, &tmp); err != nil {
+		return &ResourceError{Path: path, Err: fmt.Errorf("%w: %w", ErrDecodingTileset, err)}
 	}
 
 	*t = (Tileset)(tmp)
+	t.path = path
 	if firstGlobalID != 0 {
 		t.FirstGlobalID = firstGlobalID
 	}
+	t.warnDeprecated()
+	t.adoptTiles()
+
+	if err := t.useTileImage(); err != nil {
+		return &ResourceError{Path: path, Err: err}
+	}
 
+	return nil
+}
+
+// adoptTiles sets the Tileset of each of its Tiles
+func (t *Tileset) adoptTiles() {
+	if !t.HasTiles() {
+		return
+	}
+	for _, tile := range *t.Tiles {
+		tile.Tileset = t
+	}
+}
+
+// useTileImage lends a Tileset without an image of its own the image of its first Tile that has one
+func (t *Tileset) useTileImage() error {
 	if t.HasImage() {
 		return nil
 	}
@@ -310,6 +729,103 @@ func (t *Tileset) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 	return nil
 }
 
+// Validate checks the Tileset for internal consistency, returning an error for each mismatch between its TileCount,
+// Columns, Image size, Tile IDs and animation Frame Tile IDs. Returns `nil` if the Tileset is consistent.
+func (t *Tileset) Validate() []error {
+	var errs []error
+
+	if !t.isCollection() && t.TileWidth > 0 && t.TileHeight > 0 {
+		// Like Tiled, pixels left over past the last whole tile are ignored, so the image must fit exactly Columns
+		// whole tiles across and at least one row
+		imageColumns := (t.Image.Width - 2*t.Margin + t.Spacing) / (t.TileWidth + t.Spacing)
+		imageRows := (t.Image.Height - 2*t.Margin + t.Spacing) / (t.TileHeight + t.Spacing)
+		if t.Image.Width > 0 && (imageColumns <= 0 || t.Columns > 0 && t.Columns != imageColumns) {
+			errs = append(errs, fmt.Errorf("%w: tileset %q image width %d, tile width %d, columns %d",
+				ErrImageSizeMismatch, t.Name, t.Image.Width, t.TileWidth, t.Columns))
+		}
+		if t.Image.Height > 0 && imageRows <= 0 {
+			errs = append(errs, fmt.Errorf("%w: tileset %q image height %d, tile height %d", ErrImageSizeMismatch, t.Name,
+				t.Image.Height, t.TileHeight))
+		}
+
+		// A Tileset omitting its tilecount has no count to disagree with the grid
+		_, rows := t.GridSize()
+		if t.TileCount > 0 && t.Columns > 0 && int(t.TileCount) != t.Columns*rows {
+			errs = append(errs, fmt.Errorf("%w: tileset %q tile count %d, columns %d, rows %d", ErrTileCountMismatch,
+				t.Name, t.TileCount, t.Columns, rows))
+		}
+	}
+
+	// Collection tilesets may leave gaps in their IDs, so only image tilesets are bound by TileCount
+	if !t.isCollection() && t.HasTiles() {
+		count := t.EffectiveTileCount()
+		for _, tile := range *t.Tiles {
+			if uint32(tile.TileID) >= count {
+				errs = append(errs, fmt.Errorf("%w: tileset %q tile %d, tile count %d", ErrTileIDOutOfRange, t.Name,
+					tile.TileID, count))
+			}
+		}
+	}
+
+	errs = append(errs, t.validateFrames()...)
+
+	return errs
+}
+
+// validateFrames returns an error for each animation Frame referencing a Tile outside the Tileset. Frames of image
+// tilesets must be within the tile count and frames of collection tilesets must reference one of its Tiles.
+func (t *Tileset) validateFrames() []error {
+	if !t.HasTiles() {
+		return nil
+	}
+
+	var errs []error
+	count := t.EffectiveTileCount()
+	for _, tile := range *t.Tiles {
+		if !tile.HasAnimation() {
+			continue
+		}
+		for _, f := range *tile.Animation {
+			if t.isCollection() && t.Tiles.WithID(f.TileID) != nil ||
+				!t.isCollection() && uint32(f.TileID) < count {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%w: tileset %q tile %d frame tile %d, tile count %d",
+				ErrFrameTileIDOutOfRange, t.Name, tile.TileID, f.TileID, count))
+		}
+	}
+	return errs
+}
+
+// warnDeprecated records warnings for deprecated or missing recommended Tileset data
+func (t *Tileset) warnDeprecated() {
+	if t.TerrainTypes != nil {
+		warn("tileset %q uses deprecated terraintypes, use wangsets instead", t.Name)
+	}
+	if t.HasImage() && (t.TileCount == 0 || t.Columns == 0) {
+		warn("tileset %q is missing the recommended tilecount and columns attributes", t.Name)
+	}
+	if !t.HasImage() && !t.HasTiles() {
+		warn("tileset %q has no image and no tiles", t.Name)
+	}
+	if !t.HasTiles() {
+		return
+	}
+	for _, tile := range *t.Tiles {
+		if tile.RawTerrainType != "" {
+			warn("tileset %q tile %d uses the deprecated terrain attribute, use wangsets instead", t.Name, tile.TileID)
+		}
+		if !tile.HasAnimation() {
+			continue
+		}
+		for _, f := range *tile.Animation {
+			if f.DurationMsec <= 0 {
+				warn("tileset %q tile %d has an animation frame without a duration", t.Name, tile.TileID)
+			}
+		}
+	}
+}
+
 func (t *Tile) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 	type tempTile Tile
 	var tmp tempTile
@@ -320,6 +836,11 @@ func (t *Tile) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 
 	*t = (Tile)(tmp)
 
+	return t.finishDecode()
+}
+
+// finishDecode parses the TerrainType of a Tile read from either format from its RawTerrainType
+func (t *Tile) finishDecode() error {
 	if t.RawTerrainType == "" {
 		t.TerrainType = &TerrainType{}
 		return nil
@@ -337,6 +858,10 @@ func (t *Tile) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 
 	tid := make([]TileID, 4)
 	for i := 0; i < len(types); i++ {
+		// Tiled leaves corners without a terrain empty, as in "0,,0,"
+		if strings.TrimSpace(types[i]) == "" {
+			continue
+		}
 		id, err := strconv.ParseInt(strings.TrimSpace(types[i]), 10, 32)
 		if err != nil {
 			return err
@@ -354,11 +879,88 @@ func (t *Tile) UnmarshalXML(xd *xml.Decoder, start xml.StartElement) error {
 	return nil
 }
 
+func (w *WangSetType) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	switch s {
+	default:
+		*w = WangMixed
+		return unknownEnum(ErrUnknownWangSetType, s)
+	case "mixed":
+		*w = WangMixed
+	case "corner":
+		*w = WangCorner
+	case "edge":
+		*w = WangEdge
+	}
+	return nil
+}
+
+func (w WangSetType) MarshalText() ([]byte, error) {
+	switch w {
+	case WangMixed:
+		return []byte("mixed"), nil
+	case WangCorner:
+		return []byte("corner"), nil
+	case WangEdge:
+		return []byte("edge"), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownWangSetType, w)
+}
+
+func (r *TileRenderSize) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	switch s {
+	default:
+		*r = RenderSizeTile
+		return unknownEnum(ErrUnknownTileRenderSize, s)
+	case "tile":
+		*r = RenderSizeTile
+	case "grid":
+		*r = RenderSizeGrid
+	}
+	return nil
+}
+
+func (r TileRenderSize) MarshalText() ([]byte, error) {
+	switch r {
+	case RenderSizeTile:
+		return []byte("tile"), nil
+	case RenderSizeGrid:
+		return []byte("grid"), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownTileRenderSize, r)
+}
+
+func (f *FillMode) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	switch s {
+	default:
+		*f = Stretch
+		return unknownEnum(ErrUnknownFillMode, s)
+	case "stretch":
+		*f = Stretch
+	case "preserve-aspect-fit":
+		*f = PreserveAspectFit
+	}
+	return nil
+}
+
+func (f FillMode) MarshalText() ([]byte, error) {
+	switch f {
+	case Stretch:
+		return []byte("stretch"), nil
+	case PreserveAspectFit:
+		return []byte("preserve-aspect-fit"), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownFillMode, f)
+}
+
 func (o *ObjectAlignment) UnmarshalText(text []byte) error {
 	s := strings.ToLower(string(text))
 	switch strings.ToLower(s) {
 	default:
-		return fmt.Errorf("%w: %s", ErrUnknownObjectAlignment, s)
+		*o = Unspecified
+		return unknownEnum(ErrUnknownObjectAlignment, s)
 	case "unspecified":
 		*o = Unspecified
 	case "topleft":
@@ -382,3 +984,29 @@ func (o *ObjectAlignment) UnmarshalText(text []byte) error {
 	}
 	return nil
 }
+
+func (o ObjectAlignment) MarshalText() ([]byte, error) {
+	switch o {
+	case Unspecified:
+		return []byte("unspecified"), nil
+	case TopLeft:
+		return []byte("topleft"), nil
+	case Top:
+		return []byte("top"), nil
+	case TopRight:
+		return []byte("topright"), nil
+	case Left:
+		return []byte("left"), nil
+	case Center:
+		return []byte("center"), nil
+	case Right:
+		return []byte("right"), nil
+	case BottomLeft:
+		return []byte("bottomleft"), nil
+	case Bottom:
+		return []byte("bottom"), nil
+	case BottomRight:
+		return []byte("bottomright"), nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownObjectAlignment, o)
+}